@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/ingest"
+	"Skillfactory/36-GoNews/pkg/opml"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/storage/postgress"
+)
+
+// Справка по подкомандам
+const usage = `usage:
+  gonews                           start the news service
+  gonews opml import <file.opml>   import sources from an OPML document
+  gonews opml export [file.opml]   export sources to an OPML document (stdout by default)
+  gonews migrate up                apply pending database migrations
+  gonews migrate down [n]          revert the last n applied migrations (1 by default)
+  gonews migrate status            list migrations and whether they are applied
+`
+
+// Функция выполнения подкоманды командной строки. Возвращает код завершения процесса.
+func RunCommand(args []string) int {
+	var err error
+	switch {
+	case len(args) == 3 && args[0] == "opml" && args[1] == "import":
+		err = importOPML(args[2])
+	case len(args) == 2 && args[0] == "opml" && args[1] == "export":
+		err = exportOPML("")
+	case len(args) == 3 && args[0] == "opml" && args[1] == "export":
+		err = exportOPML(args[2])
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "up":
+		err = migrateUp()
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "migrate" && args[1] == "down":
+		steps := 1
+		if len(args) == 3 {
+			steps, err = strconv.Atoi(args[2])
+			if err != nil || steps < 1 {
+				fmt.Fprint(os.Stderr, usage)
+				return 2
+			}
+		}
+		err = migrateDown(steps)
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "status":
+		err = migrateStatus()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gonews: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// Функция импорта источников из файла OPML. Источники проверяются так же, как при добавлении через API,
+// непрошедшие проверку пропускаются. Перед импортом применяются миграции БД, как при запуске сервиса.
+func importOPML(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sources, err := opml.Parse(f)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	var valid []models.Source
+	for _, src := range sources {
+		if err := ingest.ValidateExternal(src); err != nil {
+			fmt.Fprintf(os.Stderr, "skip %s: %v\n", src.URL, err)
+			continue
+		}
+		valid = append(valid, src)
+	}
+
+	db, err := postgress.New()
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+	if _, err = db.MigrateUp(context.Background()); err != nil {
+		return err
+	}
+	added, err := db.AddSources(valid)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d of %d sources (%d already registered, %d invalid)\n",
+		added, len(sources), len(valid)-added, len(sources)-len(valid))
+	return nil
+}
+
+// Функция экспорта источников в файл OPML или в стандартный вывод
+func exportOPML(path string) error {
+	db, err := postgress.New()
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+	sources, err := db.ListSources(false)
+	if err != nil {
+		return err
+	}
+	data, err := opml.Build("GoNews subscriptions", sources)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// Функция применения непримененных миграций БД
+func migrateUp() error {
+	db, err := postgress.New()
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+	applied, err := db.MigrateUp(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("applied %d migrations\n", applied)
+	return nil
+}
+
+// Функция отката последних steps миграций БД
+func migrateDown(steps int) error {
+	db, err := postgress.New()
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+	reverted, err := db.MigrateDown(context.Background(), steps)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("reverted %d migrations\n", reverted)
+	return nil
+}
+
+// Функция вывода состояния миграций БД
+func migrateStatus() error {
+	db, err := postgress.New()
+	if err != nil {
+		return err
+	}
+	defer db.Db.Close()
+	status, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, m := range status {
+		state := "pending"
+		if m.Applied {
+			state = "applied " + time.Unix(m.AppliedAt, 0).UTC().Format(time.RFC3339)
+		}
+		name := m.Name
+		if name == "" {
+			name = "(unknown)"
+		}
+		fmt.Printf("%04d %-30s %s\n", m.Version, name, state)
+	}
+	return nil
+}
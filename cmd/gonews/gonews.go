@@ -9,13 +9,14 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"Skillfactory/36-GoNews/pkg/api"
-	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/scheduler"
 
-	DB "Skillfactory/36-GoNews/pkg/storage"
 	"Skillfactory/36-GoNews/pkg/storage/models"
 	"Skillfactory/36-GoNews/pkg/storage/postgress"
 
@@ -25,10 +26,38 @@ import (
 
 // Объект с настройками приложения
 type Config struct {
-	RSSsources []string `json:"source"`
-	Interval   int      `json:"interval"`
-	Brokers    []string `json:"brokers"`
-	Topic      []string `json:"topic"`
+	RSSsources    []SourceConfig `json:"source"` //начальный список источников, переносится в БД при первом запуске
+	Interval      int            `json:"interval"`
+	MaxConcurrent int            `json:"max_concurrent"`
+	SourcesReload int            `json:"sources_reload"`  //период перечитывания списка источников из БД в минутах
+	WebSubURL     string         `json:"websub_callback"` //внешний адрес сервиса для подписок WebSub, пустой - подписки отключены
+	Brokers       []string       `json:"brokers"`
+	Topic         []string       `json:"topic"`
+}
+
+// Настройки источника. В конфигурационном файле источник задается либо строкой с URL ленты RSS,
+// либо объектом {"url": ..., "type": ..., "interval": ..., "full_text": ..., "scrape": {...}, "json": {...}}
+// с типом источника (rss, sitemap, scrape или json), своим интервалом опроса в минутах, режимом загрузки полного
+// текста статей со страниц по ссылкам, CSS-селекторами для источников scrape и сопоставлением полей для json.
+type SourceConfig struct {
+	URL      string                `json:"url"`
+	Type     string                `json:"type"`
+	Interval int                   `json:"interval"`
+	FullText bool                  `json:"full_text"`
+	Scrape   *models.ScrapeOptions `json:"scrape"`
+	JSON     *models.JSONOptions   `json:"json"`
+	HTTP     *models.HTTPOptions   `json:"http"`
+}
+
+// Метод разбора настроек источника, заданных строкой или объектом
+func (s *SourceConfig) UnmarshalJSON(data []byte) error {
+	var url string
+	if err := json.Unmarshal(data, &url); err == nil {
+		*s = SourceConfig{URL: url}
+		return nil
+	}
+	type plain SourceConfig
+	return json.Unmarshal(data, (*plain)(s))
 }
 
 // Функция - конвертер JSON файла с настройками в объект с настройками приложения.
@@ -54,19 +83,6 @@ func ParseConfigFile(filename string) (Config, error) {
 	return config, nil
 }
 
-// Функция асинхронной обработки RSS-лент. На вход принимает источник RSS, интерфейс БД, канал для записи статей, канал для записи ошибок парсинга RSS
-func AsynParser(ctx context.Context, source string, db DB.DbInterface, news chan<- []models.NewsFullDetailed, errs chan<- error, interval int) {
-	for {
-		rssnews, err := rss.Parse(source)
-		if err != nil {
-			errs <- err
-			continue
-		}
-		news <- rssnews
-		time.Sleep(time.Duration(interval) * time.Minute)
-	}
-}
-
 // Функция принимающая сообщения из Кафки и создаеющая редирект в локалхост для срабатывания соответствующего хэндлера
 // (в зависимости от полученного сообщения)
 func SendRequestToLocalhost(path string) ([]byte, error) {
@@ -97,13 +113,22 @@ func SendRequestToLocalhost(path string) ([]byte, error) {
 }
 
 func main() {
-	ctxmain := context.Background()
+	//Подкоманды командной строки (импорт/экспорт OPML и т.д.)
+	if len(os.Args) > 1 {
+		os.Exit(RunCommand(os.Args[1:]))
+	}
+	ctxmain, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	//Подключение к новостной БД
 	pool, err := postgress.New()
 	if err != nil {
 		log.Printf("Error DB connection - %v", err)
 	}
 	defer pool.Db.Close()
+	//Применение новых миграций схемы БД. Параллельно запущенные экземпляры ждут завершения миграций.
+	if _, err = pool.MigrateUp(ctxmain); err != nil {
+		log.Fatalf("DB migration error - %v", err)
+	}
 	//Парсинг конфигурационного файла
 	config, err := ParseConfigFile("config.json")
 	if err != nil {
@@ -127,13 +152,33 @@ func main() {
 	//Канал для записи ошибок парсинга
 	errorStream := make(chan error)
 
-	for _, source := range config.RSSsources {
-		go AsynParser(ctxmain, source, pool, newsStream, errorStream, config.Interval)
+	//Перенос источников из конфигурационного файла в пустую таблицу источников
+	if err = SeedSources(pool, config.RSSsources); err != nil {
+		log.Printf("Sources seeding error - %v", err)
+	}
+	//Планировщик опроса источников, останавливается при отмене контекста.
+	//Список источников периодически перечитывается из БД, изменения применяются без перезапуска.
+	sched := scheduler.New(config.MaxConcurrent, newsStream, errorStream)
+	//Подписки WebSub на источники с хабом: новые статьи доставляются хабом сразу после публикации,
+	//опрос по расписанию остается резервным способом
+	var subs *Subscriber
+	if config.WebSubURL != "" {
+		subs = NewSubscriber(pool, config.WebSubURL)
+		go subs.Renew(ctxmain, time.Minute)
+		api.OnPush(func(sourceID int, body []byte, contentType string) {
+			go HandlePush(ctxmain, pool, sourceID, body, contentType, newsStream)
+		})
 	}
+	go WatchSources(ctxmain, pool, sched, subs, config)
 	//горутина для считывания новостей из канала и добавления их в БД
 	go func() {
 		for new := range newsStream {
-			pool.AddNews(new)
+			stats, err := pool.AddNews(new)
+			if err != nil {
+				log.Printf("Cant add news to database: %v\n", err)
+				continue
+			}
+			log.Printf("News saved: %d inserted, %d updated, %d skipped\n", stats.Inserted, stats.Updated, stats.Skipped)
 		}
 	}()
 	//горутина для считывания ошибок парсинга и логирования
@@ -202,9 +247,17 @@ func main() {
 	}
 	port := os.Getenv("PORT")
 
+	srv := &http.Server{Addr: port, Handler: router}
+	go func() {
+		<-ctxmain.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
 	log.Printf("Server gonews APP start working at port %v", port)
-	err = http.ListenAndServe(port, router)
-	if err != nil {
+	err = srv.ListenAndServe()
+	if err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/canonical"
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/fulltext"
+	"Skillfactory/36-GoNews/pkg/ingest"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/rules"
+	"Skillfactory/36-GoNews/pkg/scheduler"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/storage/postgress"
+)
+
+// Период перечитывания списка источников по умолчанию
+const defaultSourcesReload = time.Minute
+
+// Функция переносит источники из конфигурационного файла в БД, если таблица источников пуста.
+func SeedSources(db *postgress.Storage, sources []SourceConfig) error {
+	existing, err := db.ListSources(false)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	for _, source := range sources {
+		_, err = db.AddSource(models.Source{
+			URL:      source.URL,
+			Interval: source.Interval,
+			Enabled:  true,
+			Options: models.SourceOptions{
+				Type:     source.Type,
+				FullText: source.FullText,
+				Scrape:   source.Scrape,
+				JSON:     source.JSON,
+				HTTP:     source.HTTP,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Функция периодически перечитывает включенные источники из БД и передает их планировщику.
+// Блокируется до отмены контекста и остановки всех задач планировщика.
+func WatchSources(ctx context.Context, db *postgress.Storage, sched *scheduler.Scheduler, subs *Subscriber, config Config) {
+	reload := defaultSourcesReload
+	if config.SourcesReload > 0 {
+		reload = time.Duration(config.SourcesReload) * time.Minute
+	}
+	ticker := time.NewTicker(reload)
+	defer ticker.Stop()
+	for {
+		sources, err := db.ListSources(true)
+		if err != nil {
+			log.Printf("Error reading sources from DB - %v", err)
+		} else {
+			sched.Sync(ctx, SchedulerJobs(sources, config.Interval, db, subs))
+		}
+		select {
+		case <-ctx.Done():
+			sched.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Функция формирования задач планировщика из источников. Источникам без собственного интервала
+// назначается общий интервал из настроек приложения; если и он не задан, планировщик опрашивает источник
+// с минимальным интервалом scheduler.DefaultMinInterval. subs - подписчик WebSub, nil если подписки отключены.
+func SchedulerJobs(sources []models.Source, defaultInterval int, db *postgress.Storage, subs *Subscriber) []scheduler.Job {
+	var jobs []scheduler.Job
+	for _, source := range sources {
+		source := source
+		//загрузчик с настройками источника создается при первом опросе и хранит валидаторы условных запросов
+		//между опросами; источники без настроек используют общий загрузчик
+		f := sourceFetcher(source)
+		interval := source.Interval
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		jobs = append(jobs, scheduler.Job{
+			Source:   source.URL,
+			Version:  source.UpdatedAt,
+			Interval: time.Duration(interval) * time.Minute,
+			Fetch: func(ctx context.Context) ([]models.NewsFullDetailed, error) {
+				start := time.Now()
+				res, err := FetchSource(ctx, f, source, db)
+				//остановка приложения не считается ошибкой источника
+				if ctx.Err() == nil {
+					db.RecordFetch(models.FetchResult{
+						SourceID:   source.ID,
+						Time:       start.Unix(),
+						Err:        err,
+						HTTPStatus: res.StatusCode,
+						LatencyMs:  time.Since(start).Milliseconds(),
+						Items:      len(res.News),
+					})
+				}
+				if err == nil {
+					subs.Check(ctx, f, source, res)
+				}
+				return res.News, err
+			},
+		})
+	}
+	return jobs
+}
+
+// Функция загрузки статей источника загрузчиком f в соответствии с его типом и их обработки функцией
+// PrepareNews. Возвращает статьи, код ответа источника и указанный в ленте хаб WebSub.
+func FetchSource(ctx context.Context, f fetcher.Fetcher, source models.Source, db *postgress.Storage) (rss.Result, error) {
+	res, err := ingest.Fetch(ctx, f, source)
+	if err != nil || len(res.News) == 0 {
+		return res, err
+	}
+	res.News = PrepareNews(ctx, f, source, res.News, db)
+	return res, nil
+}
+
+// Загрузчик источника. Ошибка создания загрузчика с настройками источника (например не задана переменная
+// окружения с паролем) возвращается при каждом опросе и попадает в состояние источника.
+func sourceFetcher(source models.Source) fetcher.Fetcher {
+	if source.Options.HTTP == nil {
+		return rss.DefaultFetcher
+	}
+	return &lazyFetcher{opts: source.Options.HTTP}
+}
+
+// Загрузчик, создаваемый при первом запросе
+type lazyFetcher struct {
+	opts *models.HTTPOptions
+	mu   sync.Mutex
+	f    fetcher.Fetcher
+}
+
+func (l *lazyFetcher) Fetch(ctx context.Context, rawURL string) (fetcher.Response, error) {
+	f, err := l.get()
+	if err != nil {
+		return fetcher.Response{}, err
+	}
+	return f.Fetch(ctx, rawURL)
+}
+
+func (l *lazyFetcher) Remember(rawURL string, resp fetcher.Response) {
+	if f, err := l.get(); err == nil {
+		if c, ok := f.(fetcher.Conditional); ok {
+			c.Remember(rawURL, resp)
+		}
+	}
+}
+
+func (l *lazyFetcher) HTTPClient() *http.Client {
+	f, err := l.get()
+	if err != nil {
+		return nil
+	}
+	return fetcher.HTTPClient(f)
+}
+
+func (l *lazyFetcher) get() (fetcher.Fetcher, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f != nil {
+		return l.f, nil
+	}
+	f, err := rss.NewSourceFetcher(l.opts)
+	if err != nil {
+		return nil, err
+	}
+	l.f = f
+	return f, nil
+}
+
+// Функция обработки статей источника перед сохранением. Сначала применяются правила источника, затем
+// статьи помечаются ID источника, а ключом уникальности становится канонический вид ссылки (сама ссылка
+// не меняется). Для источников с режимом full_text к новым статьям догружается полный текст со страниц
+// по ссылкам, а ключом становится указанная на странице каноническая ссылка. Страницы загружаются
+// загрузчиком источника f.
+func PrepareNews(ctx context.Context, f fetcher.Fetcher, source models.Source, news []models.NewsFullDetailed, db *postgress.Storage) []models.NewsFullDetailed {
+	if len(source.Options.Rules) > 0 {
+		program, err := rules.Compile(source.Options.Rules)
+		if err != nil {
+			log.Printf("Invalid rules of source %s, rules skipped - %v", source.URL, err)
+		} else {
+			news = program.Apply(news).Kept
+		}
+	}
+	for i := range news {
+		news[i].SourceID = source.ID
+		news[i].LinkKey = canonical.URL(news[i].Link)
+	}
+	if !source.Options.FullText {
+		return news
+	}
+	//ключ сохраненной статьи мог быть взят из канонической ссылки со страницы, поэтому проверяются
+	//и ссылка, и ее канонический вид
+	links := make([]string, 0, 2*len(news))
+	for _, n := range news {
+		links = append(links, n.Link, n.LinkKey)
+	}
+	existing, err := db.ExistingLinks(links)
+	if err != nil {
+		log.Printf("Error checking stored links - %v", err)
+	}
+	known := make(map[string]bool)
+	for _, n := range news {
+		if existing[n.Link] || existing[n.LinkKey] {
+			known[n.Link] = true
+		}
+	}
+	fulltext.Enrich(ctx, f, news, func(link string) bool { return known[link] })
+	return news
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/storage/postgress"
+	"Skillfactory/36-GoNews/pkg/websub"
+)
+
+const (
+	//запрашиваемый у хаба срок подписки
+	websubLease = 7 * 24 * time.Hour
+	//подписка продлевается, если до окончания срока осталось меньше
+	websubRenewBefore = time.Hour
+	//повторный запрос к хабу, не ответившему на предыдущий, отправляется не раньше
+	websubRetry = 15 * time.Minute
+	//повторный запрос после отказа хаба отправляется не раньше
+	websubDeniedRetry = 24 * time.Hour
+)
+
+// Подписчик WebSub. Подписывается на источники, ленты которых указывают хаб, и продлевает подписки.
+// Опрос источников по расписанию при этом продолжается как резервный способ получения статей.
+type Subscriber struct {
+	db       *postgress.Storage
+	callback string //внешний адрес сервиса, к нему добавляется /websub/{id источника}
+}
+
+// Конструктор подписчика. callback - внешний адрес сервиса, доступный хабам.
+func NewSubscriber(db *postgress.Storage, callback string) *Subscriber {
+	return &Subscriber{
+		db:       db,
+		callback: strings.TrimRight(callback, "/"),
+	}
+}
+
+// Метод проверки подписки источника по результату опроса. Если лента указывает хаб, а подписки на этот хаб
+// и URL ленты еще нет, отправляется запрос подписки. Запрос к хабу выполняется HTTP-клиентом загрузчика
+// источника f (прокси, TLS и таймаут источника).
+func (s *Subscriber) Check(ctx context.Context, f fetcher.Fetcher, source models.Source, res rss.Result) {
+	if s == nil || res.Hub == "" {
+		return
+	}
+	topic := res.Self
+	if topic == "" {
+		topic = source.URL
+	}
+	sub, err := s.db.GetSubscription(source.ID)
+	if err == nil && sub.Hub == res.Hub && sub.Topic == topic {
+		return
+	}
+	if err != nil && !errors.Is(err, postgress.ErrSubscriptionNotFound) {
+		log.Printf("Error reading subscription from DB - %v", err)
+		return
+	}
+	secret, err := websub.NewSecret()
+	if err != nil {
+		log.Printf("WebSub secret generating error - %v", err)
+		return
+	}
+	s.subscribe(ctx, fetcher.HTTPClient(f), models.Subscription{
+		SourceID: source.ID,
+		Hub:      res.Hub,
+		Topic:    topic,
+		Secret:   secret,
+		State:    models.SubscriptionPending,
+	})
+}
+
+// Функция периодически продлевает истекающие подписки и повторяет неподтвержденные. Отклоненные хабом
+// подписки повторяются не чаще раза в websubDeniedRetry.
+// Блокируется до отмены контекста.
+func (s *Subscriber) Renew(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		now := time.Now()
+		subs, err := s.db.SubscriptionsToRenew(now.Add(websubRenewBefore).Unix(), now.Add(-websubRetry).Unix(),
+			now.Add(-websubDeniedRetry).Unix())
+		if err != nil {
+			log.Printf("Error reading subscriptions from DB - %v", err)
+			continue
+		}
+		for _, sub := range subs {
+			source, err := s.db.GetSource(sub.SourceID)
+			if err != nil {
+				log.Printf("Error reading source from DB - %v", err)
+				continue
+			}
+			if sub.State != models.SubscriptionActive {
+				sub.State = models.SubscriptionPending
+			}
+			s.subscribe(ctx, fetcher.HTTPClient(sourceFetcher(source)), sub)
+		}
+	}
+}
+
+// Сохранение подписки и отправка запроса хабу клиентом client. Подписка активируется после подтверждения хабом.
+func (s *Subscriber) subscribe(ctx context.Context, client *http.Client, sub models.Subscription) {
+	if err := s.db.SaveSubscription(sub); err != nil {
+		log.Printf("Error saving subscription to DB - %v", err)
+		return
+	}
+	//загрузчик источника не создан (например не задана переменная окружения с секретом)
+	if client == nil {
+		client = &http.Client{Timeout: fetcher.DefaultTimeout}
+	}
+	c := websub.Client{HTTP: client}
+	err := c.Subscribe(ctx, websub.Request{
+		Hub:      sub.Hub,
+		Topic:    sub.Topic,
+		Callback: s.callback + "/websub/" + strconv.Itoa(sub.SourceID),
+		Secret:   sub.Secret,
+		Lease:    websubLease,
+	})
+	if err != nil {
+		log.Printf("WebSub subscription error for %s - %v", sub.Topic, err)
+	}
+}
+
+// Функция обработки ленты, доставленной хабом. Статьи проходят ту же обработку, что и при опросе источника,
+// и передаются в канал новостей.
+func HandlePush(ctx context.Context, db *postgress.Storage, sourceID int, body []byte, contentType string,
+	news chan<- []models.NewsFullDetailed) {
+	source, err := db.GetSource(sourceID)
+	if err != nil || !source.Enabled {
+		return
+	}
+	items, err := rss.ParseFeed(body, contentType)
+	if err != nil {
+		log.Printf("Error parsing pushed feed %s - %v", source.URL, err)
+		return
+	}
+	if len(items) == 0 {
+		return
+	}
+	items = PrepareNews(ctx, sourceFetcher(source), source, items, db)
+	select {
+	case news <- items:
+	case <-ctx.Done():
+	}
+}
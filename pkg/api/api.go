@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"Skillfactory/36-GoNews/pkg/storage/models"
 	"Skillfactory/36-GoNews/pkg/storage/postgress"
 
 	"github.com/gorilla/mux"
@@ -14,8 +15,9 @@ import (
 
 // Объект API
 type Api struct {
-	db *postgress.Storage
-	r  *mux.Router
+	db   *postgress.Storage
+	r    *mux.Router
+	push PushHandler
 }
 
 // Конуструктор объекта API
@@ -34,12 +36,30 @@ func (api *Api) Router() *mux.Router {
 func (api *Api) endpoints() {
 	//маршрут для возврата детальной информации о новости
 	api.r.HandleFunc("/newsdetail/{id}", api.GetDetailedNewsHandler).Methods(http.MethodGet, http.MethodOptions)
+	//маршруты для возврата версий новости и разницы между двумя версиями
+	api.r.HandleFunc("/newsdetail/{id:[0-9]+}/revisions", api.RevisionsHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/newsdetail/{id:[0-9]+}/diff", api.DiffRevisionsHandler).Methods(http.MethodGet, http.MethodOptions)
 	//маршрут для возврата списка новостей
 	api.r.HandleFunc("/newslist/", http.HandlerFunc(api.GetNewsListHandler)).Methods(http.MethodGet, http.MethodOptions)
 	//маршрут для возврата списка  новостей отфильтрованных по контенту
 	api.r.HandleFunc("/newslist/filtered/", api.FilteredByContentHandler).Methods(http.MethodGet, http.MethodOptions)
 	//маршрут для возврата списка новостей отфильтрованных по дате публикации
 	api.r.HandleFunc("/newslist/filtered/date/", api.FilteredByPublishedHandler).Methods(http.MethodGet, http.MethodOptions)
+	//маршруты для управления источниками новостей. Маршруты изменения регистрируются первыми, чтобы
+	//предварительный запрос CORS (OPTIONS) получал их хэндлер с разрешенными методами
+	api.r.HandleFunc("/sources/", api.AddSourceHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.r.HandleFunc("/sources/", api.ListSourcesHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/sources/{id:[0-9]+}", api.UpdateSourceHandler).Methods(http.MethodPut, http.MethodOptions)
+	api.r.HandleFunc("/sources/{id:[0-9]+}", api.DisableSourceHandler).Methods(http.MethodDelete, http.MethodOptions)
+	api.r.HandleFunc("/sources/{id:[0-9]+}", api.GetSourceHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/sources/{id:[0-9]+}/rules/dry-run", api.RulesDryRunHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.r.HandleFunc("/sources/opml", api.ImportOPMLHandler).Methods(http.MethodPost, http.MethodOptions)
+	api.r.HandleFunc("/sources/opml", api.ExportOPMLHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/sources/discover", api.DiscoverSourcesHandler).Methods(http.MethodGet, http.MethodOptions)
+	api.r.HandleFunc("/sources/health", api.SourcesHealthHandler).Methods(http.MethodGet, http.MethodOptions)
+	//маршруты для подтверждения подписок WebSub и приема новых записей от хабов
+	api.r.HandleFunc("/websub/{id:[0-9]+}", api.WebSubVerifyHandler).Methods(http.MethodGet)
+	api.r.HandleFunc("/websub/{id:[0-9]+}", api.WebSubPushHandler).Methods(http.MethodPost)
 	//маршрут для подключения к веб-приложению
 	api.r.PathPrefix("/").Handler(http.StripPrefix("/news/", http.FileServer(http.Dir("./webapp"))))
 }
@@ -68,7 +88,9 @@ func (api *Api) GetDetailedNewsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(reqid))
 }
 
-// хэндлер отдающий список новостей с пагинацией
+// хэндлер отдающий список новостей от новых к старым с пагинацией. Параметр page - номер страницы; при наличии
+// параметра cursor (пустого для первой страницы) новости выводятся по курсору, а в ответе возвращаются курсоры
+// next_cursor и prev_cursor соседних страниц.
 func (api *Api) GetNewsListHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -76,6 +98,22 @@ func (api *Api) GetNewsListHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	collapse := collapseDuplicates(r)
+	cursor, cursorMode, err := readCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cursorMode {
+		results, more, err := api.db.GetNewsListByCursor(cursor, pagination.NEWS_PER_PAGE, collapse)
+		if err != nil {
+			http.Error(w, "failed get news from DB", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(pagination.NewCursor(results, cursor, more))
+		return
+	}
+
 	nStr := r.URL.Query().Get("n")
 	n, _ := strconv.Atoi(nStr)
 
@@ -85,7 +123,7 @@ func (api *Api) GetNewsListHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	page, _ := strconv.Atoi(pageStr)
 	pag := pagination.New(n, page)
-	results, err := api.db.GetNewsListWithPagination(n, (pag.CurrentPage-1)*pag.NewsPerPage, pag.NewsPerPage)
+	results, err := api.db.GetNewsListWithPagination(n, (pag.CurrentPage-1)*pag.NewsPerPage, pag.NewsPerPage, collapse)
 	if err != nil {
 		http.Error(w, "failed get news from DB", http.StatusInternalServerError)
 		return
@@ -95,7 +133,9 @@ func (api *Api) GetNewsListHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// хэндлер отдающий новости отфильтрованные по контенту c пагинацией
+// хэндлер полнотекстового поиска новостей c пагинацией. Параметр s - поисковый запрос (слова, "фраза", or, -слово),
+// новости возвращаются по убыванию релевантности (Rank) с фрагментами текста (Snippet), в которых найденные слова
+// выделены тегом <mark>. Как и в списке новостей, поддерживается вывод по номеру страницы (page) и по курсору (cursor).
 func (api *Api) FilteredByContentHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -104,6 +144,21 @@ func (api *Api) FilteredByContentHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	filter := r.URL.Query().Get("s")
+	collapse := collapseDuplicates(r)
+	cursor, cursorMode, err := readCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cursorMode {
+		results, more, err := api.db.SearchNewsByCursor(filter, cursor, pagination.NEWS_PER_PAGE, collapse)
+		if err != nil {
+			http.Error(w, "failed get filtered by content news from DB", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(pagination.NewCursor(results, cursor, more))
+		return
+	}
 
 	pageStr := r.URL.Query().Get("page")
 	if pageStr == "" {
@@ -111,14 +166,14 @@ func (api *Api) FilteredByContentHandler(w http.ResponseWriter, r *http.Request)
 	}
 	page, _ := strconv.Atoi(pageStr)
 	//запрос в БД необходимый для подсчета количества новостей отфильтрованных с учетом заданного фильтра
-	filteredData, err := api.db.FilterNewsByContent(filter)
+	total, err := api.db.CountNewsByContent(filter, collapse)
 	if err != nil {
 		http.Error(w, "failed get filtered by content news from DB", http.StatusInternalServerError)
 		return
 	}
 
-	pag := pagination.New(len(filteredData), page)
-	results, err := api.db.FilterNewsByContentWithPagination(filter, (pag.CurrentPage-1)*pag.NewsPerPage, pag.NewsPerPage)
+	pag := pagination.New(total, page)
+	results, err := api.db.FilterNewsByContentWithPagination(filter, (pag.CurrentPage-1)*pag.NewsPerPage, pag.NewsPerPage, collapse)
 	if err != nil {
 		http.Error(w, "failed get filtered by content news with pagination from DB", http.StatusInternalServerError)
 		return
@@ -128,7 +183,8 @@ func (api *Api) FilteredByContentHandler(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
-// хэндлер отдающий новости отфильтрованные по дате публикации
+// хэндлер отдающий новости отфильтрованные по дате публикации. При наличии параметра cursor новости выводятся
+// по курсору с курсорами next_cursor и prev_cursor соседних страниц, как в списке новостей.
 func (api *Api) FilteredByPublishedHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -137,7 +193,21 @@ func (api *Api) FilteredByPublishedHandler(w http.ResponseWriter, r *http.Reques
 	}
 	filter := r.URL.Query().Get("date")
 	filterInt, _ := strconv.Atoi(filter)
-	news, err := api.db.FilterNewsByPublished(filterInt)
+	cursor, cursorMode, err := readCursor(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if cursorMode {
+		results, more, err := api.db.FilterNewsByPublishedByCursor(filterInt, cursor, pagination.NEWS_PER_PAGE, collapseDuplicates(r))
+		if err != nil {
+			http.Error(w, "failed get filtered by published news from DB", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(pagination.NewCursor(results, cursor, more))
+		return
+	}
+	news, err := api.db.FilterNewsByPublished(filterInt, collapseDuplicates(r))
 	if err != nil {
 		http.Error(w, "failed get filtered by published news from DB", http.StatusInternalServerError)
 		return
@@ -145,3 +215,25 @@ func (api *Api) FilteredByPublishedHandler(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(news)
 	w.WriteHeader(http.StatusOK)
 }
+
+// Функция чтения параметра cursor. Возвращает курсор (nil - первая страница) и признак вывода по курсору.
+func readCursor(r *http.Request) (*models.Cursor, bool, error) {
+	if !r.URL.Query().Has("cursor") {
+		return nil, false, nil
+	}
+	s := r.URL.Query().Get("cursor")
+	if s == "" {
+		return nil, true, nil
+	}
+	c, err := pagination.DecodeCursor(s)
+	if err != nil {
+		return nil, true, err
+	}
+	return &c, true, nil
+}
+
+// Функция чтения параметра collapse: collapse=1 - не возвращать в списках почти дубликаты других статей
+func collapseDuplicates(r *http.Request) bool {
+	collapse, _ := strconv.ParseBool(r.URL.Query().Get("collapse"))
+	return collapse
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+
+	"github.com/gorilla/mux"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Граница предложения: при сравнении версий каждое предложение выносится в отдельную строку
+var sentenceEnd = regexp.MustCompile(`([.!?…])\s+`)
+
+// хэндлер отдающий все версии статьи, последняя версия - текущая
+func (api *Api) RevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	revisions, err := api.db.ListRevisions(id)
+	if err != nil {
+		http.Error(w, "failed get revisions from DB", http.StatusInternalServerError)
+		return
+	}
+	if len(revisions) == 0 {
+		http.Error(w, "news not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// хэндлер отдающий разницу двух версий статьи в формате unified diff. Параметры from и to - номера версий,
+// по умолчанию сравниваются предпоследняя и текущая версии.
+func (api *Api) DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	revisions, err := api.db.ListRevisions(id)
+	if err != nil {
+		http.Error(w, "failed get revisions from DB", http.StatusInternalServerError)
+		return
+	}
+	if len(revisions) == 0 {
+		http.Error(w, "news not found", http.StatusNotFound)
+		return
+	}
+
+	from, to := len(revisions)-1, len(revisions)
+	if from < 1 {
+		from = 1
+	}
+	if s := r.URL.Query().Get("from"); s != "" {
+		from, err = strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "invalid from revision", http.StatusBadRequest)
+			return
+		}
+	}
+	if s := r.URL.Query().Get("to"); s != "" {
+		to, err = strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "invalid to revision", http.StatusBadRequest)
+			return
+		}
+	}
+	if from < 1 || from > len(revisions) || to < 1 || to > len(revisions) {
+		http.Error(w, "revision not found", http.StatusNotFound)
+		return
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(revisionText(revisions[from-1])),
+		B:        difflib.SplitLines(revisionText(revisions[to-1])),
+		FromFile: "revision " + strconv.Itoa(from),
+		ToFile:   "revision " + strconv.Itoa(to),
+		Context:  3,
+	})
+	if err != nil {
+		http.Error(w, "failed compare revisions", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		From int    `json:"from"`
+		To   int    `json:"to"`
+		Diff string `json:"diff"`
+	}{from, to, diff})
+}
+
+// Текст версии для сравнения: заголовок и текст статьи по предложению в строке
+func revisionText(r models.Revision) string {
+	return r.Title + "\n\n" + sentenceEnd.ReplaceAllString(r.Content, "$1\n") + "\n"
+}
@@ -0,0 +1,352 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"Skillfactory/36-GoNews/pkg/ingest"
+	"Skillfactory/36-GoNews/pkg/opml"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/rules"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/storage/postgress"
+
+	"github.com/gorilla/mux"
+)
+
+// Максимальный размер импортируемого документа OPML
+const maxOPMLSize = 10 << 20
+
+// хэндлер отдающий список источников. Параметр enabled=1 - только включенные источники.
+func (api *Api) ListSourcesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	sources, err := api.db.ListSources(r.URL.Query().Get("enabled") == "1")
+	if err != nil {
+		http.Error(w, "failed get sources from DB", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(sources)
+}
+
+// хэндлер отдающий состояние опроса источников: время последней попытки и успешной загрузки, число ошибок подряд,
+// последняя ошибка, код ответа, задержка и число статей в последней загрузке. Параметр failing=1 - только источники
+// с ошибками.
+func (api *Api) SourcesHealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	health, err := api.db.ListSourceHealth()
+	if err != nil {
+		http.Error(w, "failed get sources health from DB", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("failing") == "1" {
+		failing := []models.SourceHealth{}
+		for _, h := range health {
+			if h.ConsecutiveFailures > 0 {
+				failing = append(failing, h)
+			}
+		}
+		health = failing
+	}
+	json.NewEncoder(w).Encode(health)
+}
+
+// хэндлер отдающий источник по ID
+func (api *Api) GetSourceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	src, err := api.db.GetSource(id)
+	if err != nil {
+		sourceError(w, err, "failed get source from DB")
+		return
+	}
+	json.NewEncoder(w).Encode(src)
+}
+
+// хэндлер добавления источника. Принимает JSON объекта источника, возвращает созданный источник.
+func (api *Api) AddSourceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+	src := models.Source{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "invalid source JSON", http.StatusBadRequest)
+		return
+	}
+	if err := ingest.ValidateExternal(src); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := api.db.AddSource(src)
+	if err != nil {
+		sourceError(w, err, "failed add source to DB")
+		return
+	}
+	src, err = api.db.GetSource(id)
+	if err != nil {
+		sourceError(w, err, "failed get source from DB")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(src)
+}
+
+// хэндлер обновления источника. Поля, отсутствующие в JSON, сохраняют текущие значения. Настройки TLS
+// из config.json можно оставить без изменений, но не изменить.
+func (api *Api) UpdateSourceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	src, err := api.db.GetSource(id)
+	if err != nil {
+		sourceError(w, err, "failed get source from DB")
+		return
+	}
+	stored := tlsOptions(src)
+	if err = json.NewDecoder(r.Body).Decode(&src); err != nil {
+		http.Error(w, "invalid source JSON", http.StatusBadRequest)
+		return
+	}
+	src.ID = id
+	validate := ingest.ValidateExternal
+	if reflect.DeepEqual(tlsOptions(src), stored) {
+		validate = ingest.ValidateSource
+	}
+	if err = validate(src); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err = api.db.UpdateSource(src); err != nil {
+		sourceError(w, err, "failed update source in DB")
+		return
+	}
+	src, err = api.db.GetSource(id)
+	if err != nil {
+		sourceError(w, err, "failed get source from DB")
+		return
+	}
+	json.NewEncoder(w).Encode(src)
+}
+
+// хэндлер отключения источника. Источник и его статьи остаются в БД, но источник больше не опрашивается.
+func (api *Api) DisableSourceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	if err := api.db.SetSourceEnabled(id, false); err != nil {
+		sourceError(w, err, "failed disable source in DB")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// хэндлер импорта источников из документа OPML в теле запроса. Источники с уже сохраненным URL пропускаются.
+func (api *Api) ImportOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+	sources, err := opml.Parse(http.MaxBytesReader(w, r.Body, maxOPMLSize))
+	if err != nil {
+		http.Error(w, "invalid OPML document", http.StatusBadRequest)
+		return
+	}
+	var valid []models.Source
+	for _, src := range sources {
+		if ingest.ValidateExternal(src) == nil {
+			valid = append(valid, src)
+		}
+	}
+	added, err := api.db.AddSources(valid)
+	if err != nil {
+		http.Error(w, "failed add sources to DB", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]int{
+		"total":   len(sources),
+		"added":   added,
+		"skipped": len(sources) - added,
+	})
+}
+
+// хэндлер экспорта всех источников в документ OPML
+func (api *Api) ExportOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	sources, err := api.db.ListSources(false)
+	if err != nil {
+		http.Error(w, "failed get sources from DB", http.StatusInternalServerError)
+		return
+	}
+	data, err := opml.Build("GoNews subscriptions", sources)
+	if err != nil {
+		http.Error(w, "failed build OPML document", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="gonews.opml"`)
+	w.Write(data)
+}
+
+// хэндлер поиска лент по адресу сайта (параметр url). Используется при регистрации источника,
+// когда известен только адрес сайта, а не ленты. Если указан параметр source (ID источника), запросы
+// выполняются с настройками HTTP-клиента этого источника.
+func (api *Api) DiscoverSourcesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		return
+	}
+	pageURL := r.URL.Query().Get("url")
+	if err := ingest.ValidateSource(models.Source{URL: pageURL}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var opts *models.HTTPOptions
+	if param := r.URL.Query().Get("source"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			http.Error(w, "invalid source id", http.StatusBadRequest)
+			return
+		}
+		src, err := api.db.GetSource(id)
+		if err != nil {
+			sourceError(w, err, "failed get source from DB")
+			return
+		}
+		opts = src.Options.HTTP
+	}
+	f, err := rss.NewSourceFetcher(opts)
+	if err != nil {
+		http.Error(w, "invalid source http options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	candidates, err := rss.Discover(r.Context(), f, pageURL)
+	if err != nil {
+		http.Error(w, "failed discover feeds: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	if candidates == nil {
+		candidates = []rss.Candidate{}
+	}
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// хэндлер пробного применения правил к текущим статьям источника. Статьи не сохраняются. В теле запроса
+// можно передать проверяемый список правил, иначе применяются сохраненные правила источника.
+func (api *Api) RulesDryRunHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if r.Method == http.MethodOptions {
+		//предварительный запрос CORS перед POST с телом JSON
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	src, err := api.db.GetSource(id)
+	if err != nil {
+		sourceError(w, err, "failed get source from DB")
+		return
+	}
+	var list []models.Rule
+	err = json.NewDecoder(r.Body).Decode(&list)
+	if errors.Is(err, io.EOF) {
+		list = src.Options.Rules
+	} else if err != nil {
+		http.Error(w, "invalid rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	program, err := rules.Compile(list)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	//отдельный загрузчик без сохраненных валидаторов, чтобы источник не ответил 304
+	f, err := rss.NewSourceFetcher(src.Options.HTTP)
+	if err != nil {
+		http.Error(w, "invalid source http options: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	res, err := ingest.Fetch(r.Context(), f, src)
+	if err != nil {
+		http.Error(w, "failed fetch source: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	type item struct {
+		Title  string `json:"title"`
+		Link   string `json:"link"`
+		Rule   int    `json:"rule,omitempty"`
+		Reason string `json:"reason,omitempty"`
+	}
+	applied := program.Apply(res.News)
+	report := struct {
+		Total   int    `json:"total"`
+		Kept    []item `json:"kept"`
+		Dropped []item `json:"dropped"`
+	}{Total: len(res.News), Kept: []item{}, Dropped: []item{}}
+	for _, n := range applied.Kept {
+		report.Kept = append(report.Kept, item{Title: n.Title, Link: n.Link})
+	}
+	for _, d := range applied.Dropped {
+		report.Dropped = append(report.Dropped, item{Title: d.News.Title, Link: d.News.Link, Rule: d.Rule, Reason: d.Reason})
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+// Копия настроек TLS источника. Разбор JSON в сохраненный источник изменяет его настройки на месте,
+// поэтому для сравнения нужна копия.
+func tlsOptions(src models.Source) *models.TLSOptions {
+	if src.Options.HTTP == nil || src.Options.HTTP.TLS == nil {
+		return nil
+	}
+	tls := *src.Options.HTTP.TLS
+	return &tls
+}
+
+// Функция записи ответа с ошибкой работы с источником
+func sourceError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, postgress.ErrSourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, postgress.ErrSourceExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/storage/postgress"
+	"Skillfactory/36-GoNews/pkg/websub"
+
+	"github.com/gorilla/mux"
+)
+
+// Функция обработки ленты, доставленной хабом WebSub для источника sourceID
+type PushHandler func(sourceID int, body []byte, contentType string)
+
+// Метод установки обработчика лент, доставленных хабами WebSub
+func (api *Api) OnPush(h PushHandler) {
+	api.push = h
+}
+
+// хэндлер подтверждения подписки хабом WebSub. Хаб передает hub.mode, hub.topic, hub.challenge
+// и hub.lease_seconds; при совпадении с сохраненной подпиской в ответ возвращается hub.challenge.
+func (api *Api) WebSubVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	sub, err := api.db.GetSubscription(id)
+	if errors.Is(err, postgress.ErrSubscriptionNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed get subscription from DB", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	mode := q.Get("hub.mode")
+	//запрос не про тему подписки не меняет ее состояние
+	if q.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+	if mode == websub.ModeDenied {
+		log.Printf("WebSub hub %s denied subscription for %s: %s", sub.Hub, sub.Topic, q.Get("hub.reason"))
+		err = api.db.SetSubscriptionState(id, models.SubscriptionDenied)
+		if err != nil {
+			http.Error(w, "failed update subscription in DB", http.StatusInternalServerError)
+		}
+		return
+	}
+	if q.Get("hub.challenge") == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if mode != websub.ModeSubscribe {
+		http.NotFound(w, r)
+		return
+	}
+	err = api.db.ActivateSubscription(id, websub.ParseLease(q.Get("hub.lease_seconds")))
+	if err != nil {
+		http.Error(w, "failed update subscription in DB", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, q.Get("hub.challenge"))
+}
+
+// хэндлер приема новых записей ленты от хаба WebSub. Записи с неверной подписью X-Hub-Signature
+// игнорируются, но хабу все равно возвращается 2xx, как требует протокол.
+func (api *Api) WebSubPushHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	sub, err := api.db.GetSubscription(id)
+	if errors.Is(err, postgress.ErrSubscriptionNotFound) {
+		//410 сообщает хабу, что подписки больше нет
+		http.Error(w, "subscription not found", http.StatusGone)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed get subscription from DB", http.StatusInternalServerError)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, fetcher.MaxSize))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if !websub.CheckSignature(r.Header.Get("X-Hub-Signature"), body, sub.Secret) {
+		log.Printf("WebSub notification for %s with invalid signature ignored", sub.Topic)
+		return
+	}
+	if api.push != nil {
+		api.push(id, body, r.Header.Get("Content-Type"))
+	}
+}
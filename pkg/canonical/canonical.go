@@ -0,0 +1,101 @@
+package canonical
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Параметры запроса, используемые для отслеживания переходов и не влияющие на содержимое страницы
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "yclid": true, "msclkid": true, "igshid": true,
+	"mc_cid": true, "mc_eid": true, "_ga": true, "_hsenc": true, "_hsmi": true, "ref_src": true, "spm": true,
+}
+
+// Префиксы параметров отслеживания
+var trackingPrefixes = []string{"utm_", "openstat", "_openstat"}
+
+// Функция приведения ссылки на статью к каноническому виду, который используется как ключ поиска дубликатов
+// статей. Ссылка в этом виде не загружается и не показывается читателю. Схема и хост приводятся к нижнему
+// регистру (http не заменяется на https: сайт может не поддерживать https), удаляются порт по умолчанию,
+// фрагмент, параметры отслеживания (utm_* и т.д.) и завершающий слэш пути, оставшиеся параметры сортируются. Ссылки, которые не удается разобрать
+// или которые не являются ссылками http(s), возвращаются без изменений.
+func URL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return raw
+	}
+	u.Scheme = scheme
+	u.Host = normalizeHost(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	u.User = nil
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for key := range query {
+		if isTracking(key) {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = encodeSorted(query)
+	return u.String()
+}
+
+// Функция нормализации хоста: нижний регистр, без завершающей точки и порта по умолчанию
+func normalizeHost(host string) string {
+	host = strings.ToLower(host)
+	if h, port, err := net.SplitHostPort(host); err == nil && (port == "80" || port == "443") {
+		host = h
+		if strings.Contains(h, ":") {
+			host = "[" + h + "]"
+		}
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// Функция проверки, является ли параметр запроса параметром отслеживания
+func isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if trackingParams[key] {
+		return true
+	}
+	for _, prefix := range trackingPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Функция кодирования параметров запроса в порядке сортировки ключей (значения одного ключа сохраняют порядок)
+func encodeSorted(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		for _, v := range query[key] {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
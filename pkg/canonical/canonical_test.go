@@ -0,0 +1,64 @@
+package canonical
+
+import "testing"
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "Tracking parameters removed",
+			raw:  "https://habr.com/ru/articles/853414/?utm_source=habrahabr&utm_medium=rss&utm_campaign=853414",
+			want: "https://habr.com/ru/articles/853414",
+		},
+		{
+			name: "Scheme and host normalized",
+			raw:  "HTTP://Habr.COM:80/ru/articles/853414/",
+			want: "http://habr.com/ru/articles/853414",
+		},
+		{
+			name: "Fragment removed, parameters sorted",
+			raw:  "https://golangweekly.com/issues/530?b=2&a=1&fbclid=xyz#top",
+			want: "https://golangweekly.com/issues/530?a=1&b=2",
+		},
+		{
+			name: "Root path kept",
+			raw:  "http://example.com",
+			want: "http://example.com/",
+		},
+		{
+			name: "Scheme not upgraded",
+			raw:  "HTTP://Example.com:80/a/",
+			want: "http://example.com/a",
+		},
+		{
+			name: "Default https port removed",
+			raw:  "https://example.com:443/a",
+			want: "https://example.com/a",
+		},
+		{
+			name: "Non-default port kept",
+			raw:  "https://example.com:8443/a/",
+			want: "https://example.com:8443/a",
+		},
+		{
+			name: "Not an http link",
+			raw:  "mailto:editor@example.com",
+			want: "mailto:editor@example.com",
+		},
+		{
+			name: "Empty link",
+			raw:  "",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URL(tt.raw); got != tt.want {
+				t.Errorf("URL(%q) = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package dedup
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// Максимальное расстояние Хэмминга между отпечатками, при котором статьи считаются почти одинаковыми
+const Threshold = 3
+
+// Число слов в шингле
+const shingleSize = 3
+
+// Функция расчета отпечатка статьи (simhash по шинглам из слов заголовка и текста без разметки).
+// У почти одинаковых текстов отпечатки отличаются в небольшом числе бит.
+func Fingerprint(title, content string) uint64 {
+	words := tokenize(title + " " + content)
+	if len(words) == 0 {
+		return 0
+	}
+	var weights [64]int
+	add := func(shingle string) {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if sum&(1<<uint(i)) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(words) < shingleSize {
+		add(strings.Join(words, " "))
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	var fp uint64
+	for i, w := range weights {
+		if w > 0 {
+			fp |= 1 << uint(i)
+		}
+	}
+	return fp
+}
+
+// Функция расчета расстояния Хэмминга между отпечатками
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Функция проверки, являются ли статьи с заданными отпечатками почти одинаковыми
+func Similar(a, b uint64) bool {
+	return a != 0 && b != 0 && Distance(a, b) <= Threshold
+}
+
+// Функция разбиения текста на слова в нижнем регистре (буквы и цифры)
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,38 @@
+package dedup
+
+import "testing"
+
+func TestSimilar(t *testing.T) {
+	const title = "Вышел Go 1.23"
+	const content = `Команда Go выпустила версию 1.23. В релизе появились итераторы по функциям, пакеты iter и unique,
+	а также изменения в таймерах и улучшения телеметрии. Обновление совместимо с предыдущими версиями языка.`
+
+	tests := []struct {
+		name     string
+		title    string
+		content  string
+		wantSame bool
+	}{
+		{name: "Same story, different markup and case", title: "ВЫШЕЛ Go 1.23!", content: "  " + content + " ", wantSame: true},
+		{name: "Same story with a trailing word", title: title, content: content + " Подробнее", wantSame: true},
+		{name: "Different story", title: "PostgreSQL 17", content: "Вышла новая версия PostgreSQL с улучшениями вакуума и логической репликации, инкрементальными бэкапами и JSON_TABLE.", wantSame: false},
+	}
+	base := Fingerprint(title, content)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fp := Fingerprint(tt.title, tt.content)
+			if got := Similar(base, fp); got != tt.wantSame {
+				t.Errorf("Similar() = %v (distance %d), want %v", got, Distance(base, fp), tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestFingerprint_Empty(t *testing.T) {
+	if got := Fingerprint("", " ,. "); got != 0 {
+		t.Errorf("Fingerprint() of empty text = %x, want 0", got)
+	}
+	if Similar(0, 0) {
+		t.Error("empty fingerprints must not be similar")
+	}
+}
@@ -0,0 +1,226 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Максимальный размер загружаемого документа
+const MaxSize = 20 << 20
+
+// Ошибка отсутствия документа в памяти
+var ErrNotFound = errors.New("document not found")
+
+// Загруженный документ
+type Response struct {
+	Body         []byte
+	ContentType  string //значение заголовка Content-Type, пустое для файлов
+	StatusCode   int    //код ответа источника (304 - документ не изменился, Body пустой)
+	ETag         string
+	LastModified string
+	URL          string //адрес документа после перенаправлений
+}
+
+// Интерфейс загрузчика документа по URL
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Response, error)
+}
+
+// Интерфейс загрузчика, выполняющего условные запросы. Валидаторы ответа запоминаются вызывающей стороной
+// явно, например только после успешного разбора документа.
+type Conditional interface {
+	Remember(rawURL string, resp Response)
+}
+
+// Интерфейс загрузчика, выполняющего запросы HTTP-клиентом. Клиент с настройками источника (прокси, TLS,
+// таймаут) используется и для других запросов, связанных с источником, например к хабу WebSub.
+type ClientProvider interface {
+	HTTPClient() *http.Client
+}
+
+// Функция получения HTTP-клиента загрузчика f. Возвращает nil, если загрузчик не работает по HTTP.
+func HTTPClient(f Fetcher) *http.Client {
+	if p, ok := f.(ClientProvider); ok {
+		return p.HTTPClient()
+	}
+	return nil
+}
+
+// Ошибка ответа источника с кодом вне диапазона 2xx/304
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("http error: %s", e.Status)
+}
+
+// Загрузчик по схеме URL
+type Mux map[string]Fetcher
+
+// Конструктор загрузчика http(s)://. Загрузчики File и Memory в него не входят: URL источников, элементов
+// OPML и sitemap приходят извне и не должны давать доступ к локальным файлам. Они подключаются только в тестах.
+func New(client *http.Client, userAgent string) Mux {
+	h := NewHTTP(client, userAgent)
+	return Mux{"http": h, "https": h}
+}
+
+func (m Mux) Fetch(ctx context.Context, rawURL string) (Response, error) {
+	f, err := m.fetcher(rawURL)
+	if err != nil {
+		return Response{}, err
+	}
+	return f.Fetch(ctx, rawURL)
+}
+
+func (m Mux) Remember(rawURL string, resp Response) {
+	f, err := m.fetcher(rawURL)
+	if err != nil {
+		return
+	}
+	if c, ok := f.(Conditional); ok {
+		c.Remember(rawURL, resp)
+	}
+}
+
+func (m Mux) HTTPClient() *http.Client {
+	if c := HTTPClient(m["https"]); c != nil {
+		return c
+	}
+	return HTTPClient(m["http"])
+}
+
+func (m Mux) fetcher(rawURL string) (Fetcher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := m[u.Scheme]
+	if !ok {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	return f, nil
+}
+
+// Значения заголовков ETag и Last-Modified последнего запомненного ответа
+type validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Загрузчик по HTTP. Повторные запросы выполняются условно (If-None-Match / If-Modified-Since) с валидаторами,
+// сохраненными через Remember.
+type HTTP struct {
+	Client    *http.Client
+	UserAgent string
+	Header    http.Header //дополнительные заголовки запросов
+
+	mu    sync.Mutex
+	cache map[string]validators
+}
+
+// Конструктор HTTP загрузчика
+func NewHTTP(client *http.Client, userAgent string) *HTTP {
+	return &HTTP{Client: client, UserAgent: userAgent, cache: make(map[string]validators)}
+}
+
+func (h *HTTP) Fetch(ctx context.Context, rawURL string) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Response{}, err
+	}
+	for k, v := range h.Header {
+		req.Header[k] = v
+	}
+	if h.UserAgent != "" {
+		req.Header.Set("User-Agent", h.UserAgent)
+	}
+	h.mu.Lock()
+	v := h.cache[rawURL]
+	h.mu.Unlock()
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return Response{}, err
+	}
+	defer resp.Body.Close()
+
+	res := Response{
+		ContentType:  resp.Header.Get("Content-Type"),
+		StatusCode:   resp.StatusCode,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		URL:          resp.Request.URL.String(),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return res, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return res, HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	res.Body, err = io.ReadAll(io.LimitReader(resp.Body, MaxSize))
+	return res, err
+}
+
+func (h *HTTP) HTTPClient() *http.Client {
+	return h.Client
+}
+
+func (h *HTTP) Remember(rawURL string, resp Response) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cache == nil {
+		h.cache = make(map[string]validators)
+	}
+	h.cache[rawURL] = validators{ETag: resp.ETag, LastModified: resp.LastModified}
+}
+
+// Загрузчик локальных файлов по URL вида file:///path/to/feed.xml. Относительные пути (file://testdata/feed.xml)
+// отсчитываются от рабочего каталога. Предназначен для тестов и в рабочие загрузчики не входит.
+type File struct{}
+
+func (File) Fetch(ctx context.Context, rawURL string) (Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Response{}, err
+	}
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		path = u.Host + u.Path
+	}
+	f, err := os.Open(filepath.FromSlash(path))
+	if err != nil {
+		return Response{}, err
+	}
+	defer f.Close()
+	body, err := io.ReadAll(io.LimitReader(f, MaxSize))
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Body: body, StatusCode: http.StatusOK, URL: rawURL}, nil
+}
+
+// Загрузчик документов из памяти по точному совпадению URL
+type Memory map[string][]byte
+
+func (m Memory) Fetch(ctx context.Context, rawURL string) (Response, error) {
+	body, ok := m[rawURL]
+	if !ok {
+		return Response{}, fmt.Errorf("%s: %w", rawURL, ErrNotFound)
+	}
+	return Response{Body: body, StatusCode: http.StatusOK, URL: rawURL}, nil
+}
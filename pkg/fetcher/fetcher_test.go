@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Тест проверяет, что валидаторы отправляются только после Remember, а 304 возвращается без тела
+func TestHTTP_Conditional(t *testing.T) {
+	const etag = `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "test-agent" {
+			t.Errorf("User-Agent = %q, want test-agent", r.Header.Get("User-Agent"))
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		fmt.Fprint(w, "<rss/>")
+	}))
+	defer srv.Close()
+
+	h := NewHTTP(srv.Client(), "test-agent")
+	for i := 0; i < 2; i++ {
+		resp, err := h.Fetch(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || string(resp.Body) != "<rss/>" || resp.ETag != etag {
+			t.Fatalf("Fetch() = %+v, want 200 with body before Remember", resp)
+		}
+		if resp.ContentType != "application/rss+xml; charset=utf-8" {
+			t.Errorf("ContentType = %q", resp.ContentType)
+		}
+	}
+
+	h.Remember(srv.URL, Response{ETag: etag})
+	resp, err := h.Fetch(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotModified || resp.Body != nil {
+		t.Errorf("Fetch() = %+v, want 304 without body", resp)
+	}
+}
+
+func TestHTTP_Error(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	resp, err := NewHTTP(srv.Client(), "").Fetch(context.Background(), srv.URL)
+	var httpErr HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("Fetch() error = %v, want HTTPError 404", err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestFile(t *testing.T) {
+	abs, err := filepath.Abs(filepath.Join("testdata", "doc.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "Absolute path", url: "file://" + filepath.ToSlash(abs)},
+		{name: "Relative path", url: "file://testdata/doc.txt"},
+		{name: "Missing file", url: "file://testdata/missing.txt", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := File{}.Fetch(context.Background(), tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("Fetch() error = %v, want os.ErrNotExist", err)
+				}
+				return
+			}
+			if string(resp.Body) != "hello from file\n" || resp.StatusCode != http.StatusOK {
+				t.Errorf("Fetch() = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestMux(t *testing.T) {
+	m := Mux{"mem": Memory{"mem://doc": []byte("in memory")}}
+
+	resp, err := m.Fetch(context.Background(), "mem://doc")
+	if err != nil || string(resp.Body) != "in memory" {
+		t.Errorf("Fetch() = %q, %v", resp.Body, err)
+	}
+	if _, err = m.Fetch(context.Background(), "mem://other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Fetch() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err = m.Fetch(context.Background(), "ftp://example.com/feed.xml"); err == nil {
+		t.Error("Fetch() with unsupported scheme returned no error")
+	}
+	//Remember для загрузчика без условных запросов ничего не делает
+	m.Remember("mem://doc", Response{ETag: `"v1"`})
+}
+
+// Тест проверяет, что рабочие загрузчики не читают локальные файлы
+func TestNew_NoFile(t *testing.T) {
+	path, err := filepath.Abs(filepath.Join("testdata", "doc.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withOpts, err := NewWithOptions(nil, "", &models.HTTPOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []Fetcher{New(nil, ""), withOpts} {
+		if _, err := f.Fetch(context.Background(), "file://"+filepath.ToSlash(path)); err == nil {
+			t.Error("Fetch() of file:// URL returned no error")
+		}
+	}
+}
+
+// Тест проверяет, что HTTP-клиент источника доступен через загрузчик, а у загрузчиков не по HTTP его нет
+func TestHTTPClient(t *testing.T) {
+	client := &http.Client{}
+	withOpts, err := NewWithOptions(nil, "", &models.HTTPOptions{Timeout: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := HTTPClient(New(client, "")); got != client {
+		t.Errorf("HTTPClient(New()) = %p, want %p", got, client)
+	}
+	if got := HTTPClient(withOpts); got == nil || got.Timeout != 5*time.Second {
+		t.Errorf("HTTPClient(NewWithOptions()) = %+v, want client with timeout 5s", got)
+	}
+	if got := HTTPClient(Mux{"mem": Memory{}}); got != nil {
+		t.Errorf("HTTPClient(Memory) = %+v, want nil", got)
+	}
+}
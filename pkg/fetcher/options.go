@@ -0,0 +1,217 @@
+package fetcher
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Таймаут запроса по умолчанию
+const DefaultTimeout = 30 * time.Second
+
+// Префикс переменных окружения, которые можно подставлять в настройки источника. Настройки приходят
+// и через API, поэтому остальные переменные окружения процесса (пароль БД и т.д.) недоступны.
+const SecretEnvPrefix = "GONEWS_SECRET_"
+
+// Функция проверки настроек HTTP-клиента источника без чтения переменных окружения и файлов
+func ValidateOptions(opts *models.HTTPOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.Timeout < 0 {
+		return errors.New("http timeout must not be negative")
+	}
+	if opts.Auth != nil {
+		switch opts.Auth.Type {
+		case "basic":
+			if opts.Auth.Username == "" || opts.Auth.PasswordEnv == "" {
+				return errors.New("basic auth requires username and password_env")
+			}
+			if err := allowedEnv(opts.Auth.PasswordEnv); err != nil {
+				return err
+			}
+		case "bearer":
+			if opts.Auth.TokenEnv == "" {
+				return errors.New("bearer auth requires token_env")
+			}
+			if err := allowedEnv(opts.Auth.TokenEnv); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown auth type %q", opts.Auth.Type)
+		}
+	}
+	for k, v := range opts.Headers {
+		if err := allowedRefs(v); err != nil {
+			return fmt.Errorf("header %s: %w", k, err)
+		}
+	}
+	if err := allowedRefs(opts.Proxy); err != nil {
+		return fmt.Errorf("proxy: %w", err)
+	}
+	if opts.TLS != nil && (opts.TLS.CertFile == "") != (opts.TLS.KeyFile == "") {
+		return errors.New("tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// Конструктор загрузчика http(s):// с настройками источника. Без настроек создается загрузчик
+// с клиентом client. Ошибка возвращается, если не заданы переменные окружения с секретами или не удалось
+// прочитать файлы сертификатов.
+func NewWithOptions(client *http.Client, userAgent string, opts *models.HTTPOptions) (Mux, error) {
+	if opts == nil {
+		return New(client, userAgent), nil
+	}
+	if err := ValidateOptions(opts); err != nil {
+		return nil, err
+	}
+	h := NewHTTP(nil, userAgent)
+	if opts.UserAgent != "" {
+		h.UserAgent = opts.UserAgent
+	}
+
+	h.Header = make(http.Header)
+	for k, v := range opts.Headers {
+		value, err := expandEnv(v)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", k, err)
+		}
+		h.Header.Set(k, value)
+	}
+	if opts.Auth != nil {
+		auth, err := authorization(opts.Auth)
+		if err != nil {
+			return nil, err
+		}
+		h.Header.Set("Authorization", auth)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := expandEnv(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		u, err := url.Parse(proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	if opts.TLS != nil {
+		cfg, err := tlsConfig(opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = cfg
+	}
+	timeout := DefaultTimeout
+	if opts.Timeout > 0 {
+		timeout = time.Duration(opts.Timeout) * time.Second
+	}
+	h.Client = &http.Client{Timeout: timeout, Transport: transport}
+	return Mux{"http": h, "https": h}, nil
+}
+
+// Значение заголовка Authorization
+func authorization(auth *models.AuthOptions) (string, error) {
+	switch auth.Type {
+	case "basic":
+		password, err := env(auth.PasswordEnv)
+		if err != nil {
+			return "", err
+		}
+		creds := base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + password))
+		return "Basic " + creds, nil
+	default:
+		token, err := env(auth.TokenEnv)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	}
+}
+
+func tlsConfig(opts *models.TLSOptions) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Функция проверяет, что переменную окружения можно подставлять в настройки источника
+func allowedEnv(name string) error {
+	if !strings.HasPrefix(name, SecretEnvPrefix) {
+		return fmt.Errorf("environment variable %s is not allowed, name must start with %s", name, SecretEnvPrefix)
+	}
+	return nil
+}
+
+// Функция проверки переменных окружения, на которые ссылается значение вида $NAME или ${NAME}
+func allowedRefs(s string) error {
+	var err error
+	os.Expand(s, func(name string) string {
+		if e := allowedEnv(name); e != nil && err == nil {
+			err = e
+		}
+		return ""
+	})
+	return err
+}
+
+// Значение обязательной переменной окружения с разрешенным именем
+func env(name string) (string, error) {
+	if err := allowedEnv(name); err != nil {
+		return "", err
+	}
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return v, nil
+}
+
+// Подстановка переменных окружения вида $NAME или ${NAME}
+func expandEnv(s string) (string, error) {
+	var err error
+	out := os.Expand(s, func(name string) string {
+		v, e := env(name)
+		if e != nil && err == nil {
+			err = e
+		}
+		return v
+	})
+	return out, err
+}
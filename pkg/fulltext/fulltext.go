@@ -0,0 +1,231 @@
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"Skillfactory/36-GoNews/pkg/canonical"
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Максимальный размер загружаемой страницы статьи
+const maxPageSize = 5 << 20
+
+// Минимальная длина текста абзаца, который учитывается при оценке блоков страницы
+const minParagraphLen = 25
+
+var (
+	//элементы, которые никогда не содержат текст статьи
+	unlikelyElements = "script, style, noscript, iframe, form, nav, header, footer, aside, button, svg"
+	//классы и идентификаторы блоков, которые вероятно содержат или не содержат текст статьи
+	positiveRe = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|blog|story`)
+	negativeRe = regexp.MustCompile(`(?i)comment|meta|footer|footnote|sidebar|sponsor|banner|promo|related|share|social|widget|nav|menu|subscribe|advert`)
+	spacesRe   = regexp.MustCompile(`\s+`)
+)
+
+// Статья, извлеченная со страницы
+type Article struct {
+	Text      string //основной текст статьи
+	Canonical string //ссылка из <link rel="canonical">, абсолютная после Fetch
+}
+
+// Метод загрузки страницы статьи по ссылке загрузчиком источника f и извлечения из нее основного текста.
+// Загрузчик источника передает его авторизацию, заголовки и настройки прокси и TLS.
+func Fetch(ctx context.Context, f fetcher.Fetcher, link string) (Article, error) {
+	resp, err := f.Fetch(ctx, link)
+	if err != nil {
+		return Article{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Article{}, fmt.Errorf("unexpected response code %d for %s", resp.StatusCode, link)
+	}
+	if ct := resp.ContentType; ct != "" && !strings.Contains(ct, "html") {
+		return Article{}, fmt.Errorf("unexpected content type %q for %s", ct, link)
+	}
+	body := resp.Body
+	if len(body) > maxPageSize {
+		body = body[:maxPageSize]
+	}
+	article, err := Extract(bytes.NewReader(body))
+	if err != nil {
+		return Article{}, err
+	}
+	//относительная каноническая ссылка разрешается относительно адреса страницы (после редиректов)
+	if article.Canonical != "" {
+		base := resp.URL
+		if base == "" {
+			base = link
+		}
+		ref, err := resolve(base, article.Canonical)
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") {
+			article.Canonical = ""
+		} else {
+			article.Canonical = ref.String()
+		}
+	}
+	return article, nil
+}
+
+// Функция разрешения ссылки ref относительно адреса base
+func resolve(base, ref string) (*url.URL, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	return u.Parse(ref)
+}
+
+// Метод дополняет статьи полным текстом, загруженным по их ссылкам загрузчиком источника f, а ключом уникальности
+// статьи делает каноническую ссылку со страницы (<link rel="canonical">), если она есть. Ссылка статьи не меняется. Статьи, для которых known возвращает true
+// (например, уже сохраненные в БД), пропускаются. Ошибки загрузки отдельных статей логируются и не прерывают обработку.
+func Enrich(ctx context.Context, f fetcher.Fetcher, news []models.NewsFullDetailed, known func(link string) bool) {
+	for i := range news {
+		if ctx.Err() != nil {
+			return
+		}
+		if news[i].Link == "" || (known != nil && known(news[i].Link)) {
+			continue
+		}
+		article, err := Fetch(ctx, f, news[i].Link)
+		if err != nil {
+			log.Printf("full text fetching error - %v", err)
+			continue
+		}
+		news[i].FullContent = article.Text
+		if article.Canonical != "" {
+			news[i].LinkKey = canonical.URL(article.Canonical)
+		}
+	}
+}
+
+// Функция извлечения основного текста статьи из HTML-страницы (упрощенный алгоритм readability):
+// абзацы оцениваются по длине и числу запятых, оценка передается родительским блокам с учетом их классов
+// и доли текста в ссылках, из блока с наибольшей оценкой возвращается текст его абзацев.
+// Также возвращается ссылка из <link rel="canonical"> как есть (может быть относительной).
+func Extract(r io.Reader) (Article, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return Article{}, err
+	}
+	var article Article
+	doc.Find("link[rel][href]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if rel, _ := s.Attr("rel"); strings.EqualFold(strings.TrimSpace(rel), "canonical") {
+			href, _ := s.Attr("href")
+			article.Canonical = strings.TrimSpace(href)
+			return false
+		}
+		return true
+	})
+	doc.Find(unlikelyElements).Remove()
+
+	scores := make(map[*html.Node]float64)
+	var candidates []*goquery.Selection
+	doc.Find("p, pre, td, blockquote").Each(func(_ int, p *goquery.Selection) {
+		text := normalize(p.Text())
+		if len([]rune(text)) < minParagraphLen {
+			return
+		}
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len([]rune(text)))/100, 3)
+
+		parent := p.Parent()
+		for _, weight := range []float64{1, 0.5} {
+			if parent.Length() == 0 || goquery.NodeName(parent) == "html" {
+				break
+			}
+			node := parent.Get(0)
+			if _, ok := scores[node]; !ok {
+				scores[node] = classWeight(parent)
+				candidates = append(candidates, parent)
+			}
+			scores[node] += score * weight
+			parent = parent.Parent()
+		}
+	})
+
+	var best *goquery.Selection
+	bestScore := 0.0
+	for _, c := range candidates {
+		score := scores[c.Get(0)] * (1 - linkDensity(c))
+		if best == nil || score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if best == nil {
+		return article, nil
+	}
+
+	var parts []string
+	best.Find("p, pre, li, h2, h3, h4, blockquote").Each(func(_ int, s *goquery.Selection) {
+		//вложенные блоки учитываются один раз - в составе внешнего
+		if s.ParentsFiltered("p, pre, li, blockquote").Length() > 0 {
+			return
+		}
+		text := strings.TrimSpace(s.Text())
+		if goquery.NodeName(s) != "pre" {
+			text = normalize(text)
+		}
+		if text != "" {
+			parts = append(parts, text)
+		}
+	})
+	if len(parts) == 0 {
+		article.Text = normalize(best.Text())
+	} else {
+		article.Text = strings.Join(parts, "\n\n")
+	}
+	return article, nil
+}
+
+// Функция оценки блока по его классу и идентификатору
+func classWeight(s *goquery.Selection) float64 {
+	weight := 0.0
+	for _, attr := range []string{"class", "id"} {
+		v, ok := s.Attr(attr)
+		if !ok || v == "" {
+			continue
+		}
+		if negativeRe.MatchString(v) {
+			weight -= 25
+		}
+		if positiveRe.MatchString(v) {
+			weight += 25
+		}
+	}
+	switch goquery.NodeName(s) {
+	case "article", "main":
+		weight += 10
+	case "div":
+		weight += 5
+	}
+	return weight
+}
+
+// Функция расчета доли текста блока, находящегося внутри ссылок
+func linkDensity(s *goquery.Selection) float64 {
+	total := len([]rune(normalize(s.Text())))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	s.Find("a").Each(func(_ int, a *goquery.Selection) {
+		links += len([]rune(normalize(a.Text())))
+	})
+	return float64(links) / float64(total)
+}
+
+// Функция схлопывания пробельных символов
+func normalize(s string) string {
+	return strings.TrimSpace(spacesRe.ReplaceAllString(s, " "))
+}
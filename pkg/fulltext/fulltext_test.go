@@ -0,0 +1,120 @@
+package fulltext
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"Skillfactory/36-GoNews/pkg/canonical"
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name          string
+		fixture       string
+		want          []string
+		notWant       []string
+		wantCanonical string
+	}{
+		{
+			name:    "Blog post with sidebar and comments",
+			fixture: "testdata/blog.html",
+			want: []string{
+				"Go 1.18 introduced type parameters",
+				"no reflection and no runtime cost",
+				"func Map[T, U any](s []T, f func(T) U) []U {\n\tr := make([]U, 0, len(s))",
+				"Use generics for containers and algorithms.",
+			},
+			notWant:       []string{"Popular posts", "Subscribe to our newsletter", "Great post", "Copyright", "Archive"},
+			wantCanonical: "/2024/understanding-go-generics/",
+		},
+		{
+			name:    "Cyrillic article with share panel",
+			fixture: "testdata/teaser.html",
+			want: []string{
+				"Сегодня вышел релиз Go 1.23",
+				"сборка ускорилась на несколько процентов.",
+			},
+			notWant: []string{"Поделиться", "Комментарии"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(tt.fixture)
+			if err != nil {
+				t.Fatalf("Error opening fixture - %v", err)
+			}
+			defer f.Close()
+
+			article, err := Extract(f)
+			if err != nil {
+				t.Fatalf("Extract() error - %v", err)
+			}
+			if article.Canonical != tt.wantCanonical {
+				t.Errorf("Extract() canonical = %q, want %q", article.Canonical, tt.wantCanonical)
+			}
+			got := article.Text
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("Extract() result does not contain %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("Extract() result contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	page, err := os.ReadFile("testdata/blog.html")
+	if err != nil {
+		t.Fatalf("Error reading fixture - %v", err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/post" {
+			http.NotFound(w, r)
+			return
+		}
+		//страница доступна только с заголовком авторизации источника
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	}))
+	defer srv.Close()
+
+	news := []models.NewsFullDetailed{
+		{Title: "New", Link: srv.URL + "/post"},
+		{Title: "Known", Link: srv.URL + "/post?known"},
+		{Title: "Broken", Link: srv.URL + "/missing"},
+	}
+	f := fetcher.NewHTTP(srv.Client(), "")
+	f.Header = http.Header{"Authorization": {"Bearer tok"}}
+	Enrich(context.Background(), f, news, func(link string) bool { return strings.HasSuffix(link, "?known") })
+
+	if !strings.Contains(news[0].FullContent, "Go 1.18 introduced type parameters") {
+		t.Errorf("full content of new article was not fetched: %q", news[0].FullContent)
+	}
+	if want := canonical.URL(srv.URL + "/2024/understanding-go-generics/"); news[0].LinkKey != want {
+		t.Errorf("link key was not taken from rel=canonical: got %q, want %q", news[0].LinkKey, want)
+	}
+	if want := srv.URL + "/post"; news[0].Link != want {
+		t.Errorf("link was changed: got %q, want %q", news[0].Link, want)
+	}
+	if news[1].FullContent != "" {
+		t.Errorf("known article was fetched: %q", news[1].FullContent)
+	}
+	if news[2].FullContent != "" {
+		t.Errorf("broken article got content: %q", news[2].FullContent)
+	}
+}
@@ -0,0 +1,94 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/jsonapi"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/rules"
+	"Skillfactory/36-GoNews/pkg/scrape"
+	"Skillfactory/36-GoNews/pkg/sitemap"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Функция загрузки статей источника загрузчиком f в соответствии с типом источника. Возвращает статьи
+// и код ответа источника; для лент RSS также хаб WebSub.
+func Fetch(ctx context.Context, f fetcher.Fetcher, source models.Source) (rss.Result, error) {
+	switch source.Options.Type {
+	case models.SourceSitemap:
+		news, err := sitemap.Fetch(ctx, f, source.URL)
+		return rss.Result{News: news, StatusCode: statusCode(err)}, err
+	case models.SourceScrape:
+		news, err := scrape.Fetch(ctx, f, source.URL, source.Options.Scrape)
+		return rss.Result{News: news, StatusCode: statusCode(err)}, err
+	case models.SourceJSON:
+		news, err := jsonapi.Fetch(ctx, f, source.URL, source.Options.JSON)
+		return rss.Result{News: news, StatusCode: statusCode(err)}, err
+	default:
+		return rss.FetchWith(ctx, f, source.URL)
+	}
+}
+
+// Функция проверки источника перед сохранением: адрес должен быть абсолютным http(s) URL, настройки
+// и правила - соответствовать типу источника. Используется и в API, и при импорте из OPML.
+func ValidateSource(src models.Source) error {
+	u, err := url.Parse(src.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("source url must be an absolute http(s) URL")
+	}
+	if src.Interval < 0 {
+		return errors.New("source interval must not be negative")
+	}
+	if err := Validate(src.Options); err != nil {
+		return err
+	}
+	if _, err := rules.Compile(src.Options.Rules); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Функция проверки источника, полученного через API или из OPML. Настройки TLS читают локальные файлы
+// сертификатов и ключей и могут отключать проверку сертификата сервера, поэтому задаются только в config.json.
+func ValidateExternal(src models.Source) error {
+	if err := ValidateSource(src); err != nil {
+		return err
+	}
+	if src.Options.HTTP != nil && src.Options.HTTP.TLS != nil {
+		return errors.New("http tls options can be set only in config.json")
+	}
+	return nil
+}
+
+// Функция проверки типа источника и настроек, которые требуются этому типу
+func Validate(opts models.SourceOptions) error {
+	if err := fetcher.ValidateOptions(opts.HTTP); err != nil {
+		return err
+	}
+	switch opts.Type {
+	case "", models.SourceRSS, models.SourceSitemap:
+		return nil
+	case models.SourceScrape:
+		return scrape.Validate(opts.Scrape)
+	case models.SourceJSON:
+		return jsonapi.Validate(opts.JSON)
+	}
+	return fmt.Errorf("unknown source type %q", opts.Type)
+}
+
+// Код ответа источника по результату загрузки, для которой он не возвращается явно
+func statusCode(err error) int {
+	var httpErr fetcher.HTTPError
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode
+	}
+	return 0
+}
@@ -0,0 +1,256 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/sanitize"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+
+	strip "github.com/grokify/html-strip-tags-go"
+)
+
+// Шаг пути к значению: ключ объекта или индекс массива
+type step struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// Функция проверки сопоставления полей источника
+func Validate(opts *models.JSONOptions) error {
+	if opts == nil {
+		return errors.New("json field mapping is required")
+	}
+	if opts.Title == "" || opts.Link == "" {
+		return errors.New("json title and link paths are required")
+	}
+	for name, p := range map[string]string{
+		"items": opts.Items, "title": opts.Title, "link": opts.Link, "content": opts.Content,
+		"published": opts.Published, "author": opts.Author, "categories": opts.Categories,
+		"image": opts.Image, "guid": opts.GUID,
+	} {
+		if _, err := parsePath(p); err != nil {
+			return fmt.Errorf("invalid json %s path %q: %w", name, p, err)
+		}
+	}
+	return nil
+}
+
+// Функция загрузки ответа JSON API и его разбора функцией Parse. Если ответ не изменился (304),
+// возвращается пустой слайс без ошибки.
+func Fetch(ctx context.Context, f fetcher.Fetcher, source string, opts *models.JSONOptions) ([]models.NewsFullDetailed, error) {
+	if err := Validate(opts); err != nil {
+		return nil, err
+	}
+	resp, err := f.Fetch(ctx, source)
+	if err != nil || len(resp.Body) == 0 {
+		return nil, err
+	}
+	base, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	news, err := Parse(resp.Body, base, *opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if c, ok := f.(fetcher.Conditional); ok {
+		c.Remember(source, resp)
+	}
+	return news, nil
+}
+
+// Функция разбора документа JSON в статьи по сопоставлению полей. HTML-сущности в заголовке и тексте
+// раскрываются, относительные ссылки разрешаются относительно base. Элементы без заголовка или ссылки пропускаются.
+func Parse(body []byte, base *url.URL, opts models.JSONOptions) ([]models.NewsFullDetailed, error) {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	items, err := lookup(doc, opts.Items)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := items.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items path %q is not an array", opts.Items)
+	}
+
+	fetched := time.Now()
+	var news []models.NewsFullDetailed
+	for _, item := range list {
+		n := models.NewsFullDetailed{
+			Title:  strings.TrimSpace(html.UnescapeString(str(item, opts.Title))),
+			Link:   resolve(base, str(item, opts.Link)),
+			Author: strings.TrimSpace(html.UnescapeString(str(item, opts.Author))),
+			GUID:   strings.TrimSpace(str(item, opts.GUID)),
+		}
+		if n.Title == "" || n.Link == "" {
+			continue
+		}
+		if content := str(item, opts.Content); content != "" {
+			n.ContentHTML = sanitize.HTML(content)
+			n.Content = strings.Join(strings.Fields(html.UnescapeString(strip.StripTags(content))), " ")
+		}
+		var t time.Time
+		var rule string
+		if d, ok := date(item, opts.Published, opts.DateLayout); ok {
+			t, rule = d, rss.DateFromPublished
+		}
+		n.Published, n.PublishedSource = rss.CheckDate(t, rule, fetched)
+		n.Categories = categories(item, opts.Categories)
+		if opts.Image != "" {
+			n.ImageURL = resolve(base, str(item, opts.Image))
+		}
+		news = append(news, n)
+	}
+	return news, nil
+}
+
+// Разбор пути вида "$.data.items[0].title". Пустой путь и "$" указывают на корень.
+func parsePath(p string) ([]step, error) {
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "$"), ".")
+	var steps []step
+	if p == "" {
+		return steps, nil
+	}
+	for _, seg := range strings.Split(p, ".") {
+		key, rest, _ := strings.Cut(seg, "[")
+		if key == "" && rest == "" {
+			return nil, errors.New("empty path segment")
+		}
+		if key != "" {
+			steps = append(steps, step{key: key})
+		}
+		for rest != "" {
+			idx, tail, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, errors.New("unclosed index")
+			}
+			i, err := strconv.Atoi(idx)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index %q", idx)
+			}
+			steps = append(steps, step{index: i, isIndex: true})
+			if tail != "" && !strings.HasPrefix(tail, "[") {
+				return nil, fmt.Errorf("unexpected %q after index", tail)
+			}
+			rest = strings.TrimPrefix(tail, "[")
+		}
+	}
+	return steps, nil
+}
+
+// Значение по пути от v. Отсутствующее значение - nil без ошибки.
+func lookup(v interface{}, p string) (interface{}, error) {
+	steps, err := parsePath(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range steps {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			if s.isIndex {
+				return nil, nil
+			}
+			v = node[s.key]
+		case []interface{}:
+			if !s.isIndex || s.index >= len(node) {
+				return nil, nil
+			}
+			v = node[s.index]
+		default:
+			return nil, nil
+		}
+	}
+	return v, nil
+}
+
+// Строковое значение по пути: строки, числа и логические значения; для остальных - пустая строка
+func str(v interface{}, p string) string {
+	if p == "" {
+		return ""
+	}
+	v, _ = lookup(v, p)
+	switch val := v.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return ""
+}
+
+// Дата по пути: число секунд или миллисекунд Unix, строка формата layout или известного формата
+func date(v interface{}, p, layout string) (time.Time, bool) {
+	s := strings.TrimSpace(str(v, p))
+	if s == "" {
+		return time.Time{}, false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		//больше 1e11 секунд - это уже 5138 год, значит время в миллисекундах
+		if n > 1e11 {
+			return time.UnixMilli(n).UTC(), true
+		}
+		return time.Unix(n, 0).UTC(), true
+	}
+	if layout != "" {
+		t, err := time.Parse(layout, s)
+		return t, err == nil
+	}
+	return rss.ParseDate(s)
+}
+
+// Категории по пути: массив строк или строка с категориями через запятую
+func categories(v interface{}, p string) []string {
+	if p == "" {
+		return nil
+	}
+	v, _ = lookup(v, p)
+	var raw []string
+	switch val := v.(type) {
+	case string:
+		raw = strings.Split(val, ",")
+	case []interface{}:
+		for _, c := range val {
+			if s, ok := c.(string); ok {
+				raw = append(raw, s)
+			}
+		}
+	}
+	var result []string
+	for _, c := range raw {
+		if c = strings.TrimSpace(c); c != "" {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Абсолютная ссылка http(s) относительно адреса источника, иначе пустая строка
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -0,0 +1,147 @@
+package jsonapi
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://blog.example.com/wp-json/wp/v2/posts?_embed")
+	tests := []struct {
+		name string
+		file string
+		opts models.JSONOptions
+		want []models.NewsFullDetailed
+	}{
+		{
+			name: "Nested items with nulls",
+			file: "hn-search.json",
+			opts: models.JSONOptions{
+				Items:     "$.hits",
+				Title:     "title",
+				Link:      "url",
+				Content:   "story_text",
+				Published: "created_at",
+				Author:    "author",
+				GUID:      "objectID",
+			},
+			want: []models.NewsFullDetailed{{
+				Title:           "Go 1.23.3 is released",
+				Link:            "https://go.dev/doc/devel/release#go1.23.3",
+				Author:          "spf13",
+				GUID:            "41984000",
+				Published:       time.Date(2024, 10, 29, 11, 20, 40, 0, time.UTC).Unix(),
+				PublishedSource: rss.DateFromPublished,
+			}},
+		},
+		{
+			name: "Root array with nested fields and custom layout",
+			file: "wp-posts.json",
+			opts: models.JSONOptions{
+				Title:      "title.rendered",
+				Link:       "link",
+				Content:    "excerpt.rendered",
+				Published:  "date",
+				DateLayout: "02.01.2006 15:04",
+				Author:     "_embedded.author[0].name",
+				Categories: "tags",
+				Image:      "_embedded.wp:featuredmedia[0].source_url",
+				GUID:       "id",
+			},
+			want: []models.NewsFullDetailed{{
+				Title:           "Release notes – October",
+				Link:            "https://blog.example.com/2024/10/29/release-notes/",
+				Content:         "What’s new this month.",
+				ContentHTML:     "<p>What’s new this month.</p>",
+				Author:          "Editorial team",
+				GUID:            "1042",
+				Categories:      []string{"releases", "changelog"},
+				ImageURL:        "https://blog.example.com/wp-content/uploads/2024/10/cover.png",
+				Published:       time.Date(2024, 10, 29, 14, 20, 0, 0, time.UTC).Unix(),
+				PublishedSource: rss.DateFromPublished,
+			}},
+		},
+		{
+			name: "Unix milliseconds",
+			file: "wp-posts.json",
+			opts: models.JSONOptions{Title: "title.rendered", Link: "link", Published: "modified_gmt"},
+			want: []models.NewsFullDetailed{{
+				Title:           "Release notes – October",
+				Link:            "https://blog.example.com/2024/10/29/release-notes/",
+				Published:       1730200840,
+				PublishedSource: rss.DateFromPublished,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(readFile(t, tt.file), base, tt.opts)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	f := fetcher.Memory{
+		"https://hn.example.com/search": readFile(t, "hn-search.json"),
+		"https://hn.example.com/object": []byte(`{"hits": {"title": "not an array"}}`),
+		"https://hn.example.com/broken": []byte(`{"hits": [`),
+	}
+	opts := &models.JSONOptions{Items: "hits", Title: "title", Link: "url"}
+
+	news, err := Fetch(context.Background(), f, "https://hn.example.com/search", opts)
+	if err != nil || len(news) != 1 {
+		t.Errorf("Fetch() = %d items, %v, want 1 item", len(news), err)
+	}
+	for _, source := range []string{"https://hn.example.com/object", "https://hn.example.com/broken"} {
+		if _, err = Fetch(context.Background(), f, source, opts); err == nil {
+			t.Errorf("Fetch(%s) returned no error", source)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *models.JSONOptions
+		wantErr bool
+	}{
+		{name: "Valid", opts: &models.JSONOptions{Items: "data.items", Title: "title", Link: "links[0].href"}},
+		{name: "Root array", opts: &models.JSONOptions{Title: "title", Link: "url"}},
+		{name: "Missing mapping", opts: nil, wantErr: true},
+		{name: "Missing link", opts: &models.JSONOptions{Items: "items", Title: "title"}, wantErr: true},
+		{name: "Unclosed index", opts: &models.JSONOptions{Items: "items[0", Title: "title", Link: "url"}, wantErr: true},
+		{name: "Invalid index", opts: &models.JSONOptions{Items: "items[x]", Title: "title", Link: "url"}, wantErr: true},
+		{name: "Empty segment", opts: &models.JSONOptions{Items: "data..items", Title: "title", Link: "url"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package opml
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Разделитель вложенных папок в названии группы источника. Разделитель и обратная косая черта в названии
+// папки экранируются обратной косой чертой.
+const GroupSeparator = "/"
+
+// Функция формирования группы источника из пути папок
+func JoinGroup(path []string) string {
+	parts := make([]string, len(path))
+	for i, name := range path {
+		name = strings.ReplaceAll(name, `\`, `\\`)
+		parts[i] = strings.ReplaceAll(name, GroupSeparator, `\`+GroupSeparator)
+	}
+	return strings.Join(parts, GroupSeparator)
+}
+
+// Функция разбора группы источника в путь папок. Обратная косая черта перед другим символом
+// или в конце строки сохраняется как есть.
+func SplitGroup(group string) []string {
+	if group == "" {
+		return nil
+	}
+	var path []string
+	var name strings.Builder
+	for i := 0; i < len(group); i++ {
+		switch {
+		case group[i] == '\\' && i+1 < len(group) && (group[i+1] == '\\' || group[i+1] == GroupSeparator[0]):
+			i++
+			name.WriteByte(group[i])
+		case group[i] == GroupSeparator[0]:
+			path = append(path, name.String())
+			name.Reset()
+		default:
+			name.WriteByte(group[i])
+		}
+	}
+	return append(path, name.String())
+}
+
+// Документ OPML 2.0
+type Document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+// Заголовок документа OPML
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// Тело документа OPML
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Элемент outline: лента (есть xmlUrl) или папка (есть вложенные элементы). Настройки источника, которых
+// нет в стандарте, хранятся в атрибуте options пространства имен http://gonews.local/xmlns/gonews.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Options  string    `xml:"http://gonews.local/xmlns/gonews options,attr,omitempty"` //настройки источника в JSON без типа
+	Outlines []Outline `xml:"outline"`
+}
+
+// Функция разбора документа OPML в список источников. Папки, в которых лежат ленты, сохраняются
+// в поле Group источника (вложенные папки через "/", см. JoinGroup). Тип источника читается из атрибута type
+// (неизвестные типы считаются rss), настройки - из атрибута gonews:options. Источники возвращаются включенными.
+func Parse(r io.Reader) ([]models.Source, error) {
+	var doc Document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.XMLName.Local != "opml" {
+		return nil, errors.New("not an OPML document")
+	}
+	var sources []models.Source
+	if err := walk(doc.Body.Outlines, nil, &sources); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Функция обхода дерева элементов outline
+func walk(outlines []Outline, path []string, sources *[]models.Source) error {
+	for _, o := range outlines {
+		title := o.Title
+		if title == "" {
+			title = o.Text
+		}
+		if o.XMLURL != "" {
+			var opts models.SourceOptions
+			if o.Options != "" {
+				if err := json.Unmarshal([]byte(o.Options), &opts); err != nil {
+					return fmt.Errorf("outline %s: invalid options: %w", o.XMLURL, err)
+				}
+			}
+			opts.Type = ""
+			switch o.Type {
+			case models.SourceSitemap, models.SourceScrape, models.SourceJSON:
+				opts.Type = o.Type
+			}
+			*sources = append(*sources, models.Source{
+				URL:     strings.TrimSpace(o.XMLURL),
+				Title:   title,
+				Group:   JoinGroup(path),
+				Enabled: true,
+				Options: opts,
+			})
+		}
+		if len(o.Outlines) > 0 {
+			next := path
+			if o.XMLURL == "" {
+				next = append(append([]string{}, path...), title)
+			}
+			if err := walk(o.Outlines, next, sources); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Функция формирования документа OPML 2.0 из списка источников. Группы источников становятся папками.
+// Тип источника записывается в атрибут type, остальные настройки - в атрибут gonews:options. Настройки TLS
+// не экспортируются: они задаются только в config.json, и импорт источника с ними отклоняется.
+func Build(title string, sources []models.Source) ([]byte, error) {
+	byGroup := make(map[string][]Outline)
+	for _, src := range sources {
+		text := src.Title
+		if text == "" {
+			text = src.URL
+		}
+		o := Outline{Text: text, Title: src.Title, Type: src.Options.Type, XMLURL: src.URL}
+		if o.Type == "" {
+			o.Type = models.SourceRSS
+		}
+		opts := src.Options
+		opts.Type = ""
+		if opts.HTTP != nil && opts.HTTP.TLS != nil {
+			h := *opts.HTTP
+			h.TLS = nil
+			opts.HTTP = &h
+		}
+		if !reflect.DeepEqual(opts, models.SourceOptions{}) {
+			data, err := json.Marshal(opts)
+			if err != nil {
+				return nil, err
+			}
+			o.Options = string(data)
+		}
+		//группа приводится к виду JoinGroup, чтобы папки совпадали при одинаковом пути
+		group := JoinGroup(SplitGroup(src.Group))
+		byGroup[group] = append(byGroup[group], o)
+	}
+
+	doc := Document{
+		Version: "2.0",
+		Head:    Head{Title: title, DateCreated: time.Now().UTC().Format(time.RFC1123Z)},
+		Body:    Body{Outlines: children(nil, byGroup)},
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// Функция возвращает содержимое папки с путем path: вложенные папки (по алфавиту), затем ленты
+func children(path []string, byGroup map[string][]Outline) []Outline {
+	seen := make(map[string]bool)
+	var names []string
+	for g := range byGroup {
+		p := SplitGroup(g)
+		if !inFolder(p, path) {
+			continue
+		}
+		name := p[len(path)]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var outlines []Outline
+	for _, name := range names {
+		next := append(append([]string{}, path...), name)
+		outlines = append(outlines, Outline{Text: name, Title: name, Outlines: children(next, byGroup)})
+	}
+	return append(outlines, byGroup[JoinGroup(path)]...)
+}
+
+// Функция проверяет, что группа с путем p лежит внутри папки с путем path
+func inFolder(p, path []string) bool {
+	if len(p) <= len(path) {
+		return false
+	}
+	for i := range path {
+		if p[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
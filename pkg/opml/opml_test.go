@@ -0,0 +1,173 @@
+package opml
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open("testdata/subscriptions.opml")
+	if err != nil {
+		t.Fatalf("Error opening fixture - %v", err)
+	}
+	defer f.Close()
+
+	got, err := Parse(f)
+	if err != nil {
+		t.Fatalf("Parse() error - %v", err)
+	}
+	want := []models.Source{
+		{URL: "https://habr.com/ru/rss/hub/go/all/?fl=ru", Title: "Habr: Go", Group: "Go", Enabled: true},
+		{URL: "https://cprss.s3.amazonaws.com/golangweekly.com.xml", Title: "Golang Weekly", Group: "Go/Weekly", Enabled: true},
+		{URL: "https://habr.com/ru/rss/best/daily/?fl=ru", Title: "Habr best", Enabled: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestParse_NotOPML(t *testing.T) {
+	_, err := Parse(bytes.NewBufferString(`<rss version="2.0"><channel></channel></rss>`))
+	if err == nil {
+		t.Error("Parse() of RSS document returned no error")
+	}
+}
+
+// Тест проверяет, что экспортированный документ импортируется обратно без потери групп
+func TestBuild_RoundTrip(t *testing.T) {
+	sources := []models.Source{
+		{URL: "https://example.com/a.xml", Title: "A", Group: "Go/Weekly", Enabled: true},
+		{URL: "https://example.com/b.xml", Title: "B", Enabled: true},
+		{URL: "https://example.com/c.xml", Title: "C", Group: "Go", Enabled: true},
+		{URL: "https://example.com/d.xml", Title: "D", Group: "News", Enabled: true},
+	}
+	data, err := Build("GoNews", sources)
+	if err != nil {
+		t.Fatalf("Build() error - %v", err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error - %v\n%s", err, data)
+	}
+	//папки идут перед лентами, поэтому порядок источников меняется
+	want := []models.Source{sources[0], sources[2], sources[3], sources[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %v, want %v\n%s", got, want, data)
+	}
+}
+
+// Тест проверяет, что тип и настройки источника сохраняются при экспорте и импорте
+func TestBuild_RoundTripOptions(t *testing.T) {
+	sources := []models.Source{
+		{URL: "https://example.com/sitemap.xml", Title: "Sitemap", Enabled: true,
+			Options: models.SourceOptions{Type: models.SourceSitemap, FullText: true}},
+		{URL: "https://example.com/api/news", Title: "API", Enabled: true, Options: models.SourceOptions{
+			Type: models.SourceJSON,
+			JSON: &models.JSONOptions{Items: "data", Title: "title", Link: "url"},
+			HTTP: &models.HTTPOptions{Auth: &models.AuthOptions{Type: "bearer", TokenEnv: "GONEWS_SECRET_API_TOKEN"}, Timeout: 10},
+		}},
+		{URL: "https://example.com/feed.xml", Title: "Feed", Enabled: true, Options: models.SourceOptions{
+			Rules: []models.Rule{{Action: "exclude", Field: "title", Match: "реклама"}},
+		}},
+	}
+	data, err := Build("GoNews", sources)
+	if err != nil {
+		t.Fatalf("Build() error - %v", err)
+	}
+	if !bytes.Contains(data, []byte(`type="sitemap"`)) || !bytes.Contains(data, []byte(`type="rss"`)) {
+		t.Errorf("Build() did not export source types:\n%s", data)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error - %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, sources) {
+		t.Errorf("round trip = %+v, want %+v\n%s", got, sources, data)
+	}
+}
+
+func TestParse_InvalidOptions(t *testing.T) {
+	doc := `<opml version="2.0" xmlns:gonews="http://gonews.local/xmlns/gonews"><body>
+	<outline text="A" type="rss" xmlUrl="https://example.com/a.xml" gonews:options="{broken"/></body></opml>`
+	if _, err := Parse(bytes.NewBufferString(doc)); err == nil {
+		t.Error("Parse() with invalid options returned no error")
+	}
+}
+
+// Тест проверяет, что настройки TLS не попадают в экспортированный документ, а исходный источник не меняется
+func TestBuild_NoTLS(t *testing.T) {
+	tls := &models.TLSOptions{CAFile: "/etc/gonews/ca.pem", InsecureSkipVerify: true}
+	sources := []models.Source{{URL: "https://example.com/a.xml", Title: "A", Enabled: true,
+		Options: models.SourceOptions{HTTP: &models.HTTPOptions{Timeout: 10, TLS: tls}}}}
+	data, err := Build("GoNews", sources)
+	if err != nil {
+		t.Fatalf("Build() error - %v", err)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error - %v\n%s", err, data)
+	}
+	if len(got) != 1 || got[0].Options.HTTP == nil || got[0].Options.HTTP.TLS != nil || got[0].Options.HTTP.Timeout != 10 {
+		t.Errorf("round trip = %+v\n%s", got, data)
+	}
+	if sources[0].Options.HTTP.TLS != tls {
+		t.Error("Build() modified source options")
+	}
+}
+
+// Тест проверяет, что "/" в названии папки не делит ее на вложенные папки при импорте и экспорте
+func TestBuild_SeparatorInFolderTitle(t *testing.T) {
+	doc := `<?xml version="1.0"?><opml version="2.0"><body>
+	<outline text="CI/CD"><outline text="Blog" xmlUrl="https://example.com/ci.xml"/></outline>
+	</body></opml>`
+	sources, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse() error - %v", err)
+	}
+	if len(sources) != 1 || sources[0].Group != `CI\/CD` {
+		t.Fatalf("Parse() = %v, want one source in group CI\\/CD", sources)
+	}
+	data, err := Build("GoNews", sources)
+	if err != nil {
+		t.Fatalf("Build() error - %v", err)
+	}
+	if !strings.Contains(string(data), `<outline text="CI/CD" title="CI/CD">`) {
+		t.Errorf("Build() did not keep folder CI/CD:\n%s", data)
+	}
+	got, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse() error - %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, sources) {
+		t.Errorf("round trip = %v, want %v\n%s", got, sources, data)
+	}
+}
+
+func TestSplitGroup(t *testing.T) {
+	tests := []struct {
+		group string
+		want  []string
+	}{
+		{group: "", want: nil},
+		{group: "Go/Weekly", want: []string{"Go", "Weekly"}},
+		{group: `CI\/CD/Tools`, want: []string{"CI/CD", "Tools"}},
+		{group: `C:\\Feeds`, want: []string{`C:\Feeds`}},
+		{group: `AC\DC`, want: []string{`AC\DC`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			got := SplitGroup(tt.group)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitGroup() = %q, want %q", got, tt.want)
+			}
+			if got := SplitGroup(JoinGroup(tt.want)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitGroup(JoinGroup()) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
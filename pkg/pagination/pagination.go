@@ -1,6 +1,12 @@
 package pagination
 
-import "Skillfactory/36-GoNews/pkg/storage/models"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
 
 // Число новостей на одной страннице
 const (
@@ -26,3 +32,55 @@ func New(totalResults, currentPage int) *models.Pagination {
 		NewsPerPage:  NEWS_PER_PAGE,
 	}
 }
+
+// Функция кодирования курсора в непрозрачную строку для передачи клиенту
+func EncodeCursor(c models.Cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Функция разбора курсора, полученного от клиента
+func DecodeCursor(s string) (models.Cursor, error) {
+	var c models.Cursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err = json.Unmarshal(data, &c); err != nil || c.ID < 1 {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// Конструктор объекта пагинации по курсору. cursor - курсор запрошенной страницы (nil - первая страница),
+// more - есть ли статьи за пределами страницы в направлении курсора.
+func NewCursor(results []models.NewsFullDetailed, cursor *models.Cursor, more bool) *models.Pagination {
+	pag := &models.Pagination{NewsPerPage: NEWS_PER_PAGE, Results: results}
+	if len(results) == 0 {
+		//за пределами списка: возврат к позиции курсора
+		if cursor != nil {
+			back := *cursor
+			back.Before = !back.Before
+			if back.Before {
+				pag.PrevCursor = EncodeCursor(back)
+			} else {
+				pag.NextCursor = EncodeCursor(back)
+			}
+		}
+		return pag
+	}
+	first, last := results[0], results[len(results)-1]
+	//страница получена по курсору: в обратном направлении статьи есть
+	backward := cursor != nil
+	hasNext, hasPrev := more, backward
+	if cursor != nil && cursor.Before {
+		hasNext, hasPrev = backward, more
+	}
+	if hasNext {
+		pag.NextCursor = EncodeCursor(models.Cursor{Rank: last.Rank, Published: last.Published, ID: last.ID})
+	}
+	if hasPrev {
+		pag.PrevCursor = EncodeCursor(models.Cursor{Rank: first.Rank, Published: first.Published, ID: first.ID, Before: true})
+	}
+	return pag
+}
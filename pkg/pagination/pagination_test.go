@@ -0,0 +1,61 @@
+package pagination
+
+import (
+	"testing"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	tests := []models.Cursor{
+		{Published: 1729584999, ID: 42},
+		{Published: 1729584999, ID: 42, Before: true},
+		{Rank: 0.0607927, Published: 1729584999, ID: 7},
+	}
+	for _, c := range tests {
+		got, err := DecodeCursor(EncodeCursor(c))
+		if err != nil {
+			t.Fatalf("DecodeCursor(EncodeCursor(%+v)) error = %v", c, err)
+		}
+		if got != c {
+			t.Errorf("DecodeCursor(EncodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, s := range []string{"not base64!", "bm90IGpzb24", EncodeCursor(models.Cursor{Published: 1})} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("DecodeCursor(%q) error = nil, want error", s)
+		}
+	}
+}
+
+// Тест проверяет, какие курсоры соседних страниц возвращаются в зависимости от направления и наличия статей
+func TestNewCursor(t *testing.T) {
+	results := []models.NewsFullDetailed{{ID: 3, Published: 300}, {ID: 2, Published: 200}}
+	next := EncodeCursor(models.Cursor{Published: 200, ID: 2})
+	prev := EncodeCursor(models.Cursor{Published: 300, ID: 3, Before: true})
+	tests := []struct {
+		name     string
+		cursor   *models.Cursor
+		more     bool
+		wantNext string
+		wantPrev string
+	}{
+		{name: "первая страница", cursor: nil, more: true, wantNext: next},
+		{name: "единственная страница", cursor: nil, more: false},
+		{name: "следующая страница", cursor: &models.Cursor{Published: 400, ID: 4}, more: true, wantNext: next, wantPrev: prev},
+		{name: "последняя страница", cursor: &models.Cursor{Published: 400, ID: 4}, more: false, wantPrev: prev},
+		{name: "предыдущая страница", cursor: &models.Cursor{Published: 100, ID: 1, Before: true}, more: true, wantNext: next, wantPrev: prev},
+		{name: "предыдущая первая страница", cursor: &models.Cursor{Published: 100, ID: 1, Before: true}, more: false, wantNext: next},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pag := NewCursor(results, tt.cursor, tt.more)
+			if pag.NextCursor != tt.wantNext || pag.PrevCursor != tt.wantPrev {
+				t.Errorf("NewCursor() next = %q, prev = %q, want %q, %q", pag.NextCursor, pag.PrevCursor, tt.wantNext, tt.wantPrev)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package rss
+
+import (
+	"bytes"
+	"mime"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	//атрибут encoding в XML-прологе
+	prologEncodingRe = regexp.MustCompile(`^(\s*<\?xml[^>]*?\sencoding\s*=\s*)(["'])([A-Za-z0-9._:-]+)(["'])`)
+)
+
+// Функция перекодирования ленты в UTF-8. Кодировка определяется по BOM, затем по параметру charset
+// заголовка Content-Type, затем по атрибуту encoding XML-пролога. После перекодирования кодировка в прологе
+// заменяется на UTF-8, чтобы парсер не перекодировал документ повторно.
+func toUTF8(body []byte, contentType string) ([]byte, error) {
+	enc, body := detectEncoding(body, contentType)
+	if enc != nil && enc != unicode.UTF8 {
+		decoded, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			return nil, err
+		}
+		body = decoded
+	}
+	return prologEncodingRe.ReplaceAll(body, []byte("${1}${2}UTF-8${4}")), nil
+}
+
+// Функция определения кодировки документа. Возвращает кодировку (nil - неизвестна, считается UTF-8)
+// и документ без BOM.
+func detectEncoding(body []byte, contentType string) (encoding.Encoding, []byte) {
+	switch {
+	case bytes.HasPrefix(body, bomUTF8):
+		return unicode.UTF8, body[len(bomUTF8):]
+	case bytes.HasPrefix(body, bomUTF16LE):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), body[len(bomUTF16LE):]
+	case bytes.HasPrefix(body, bomUTF16BE):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), body[len(bomUTF16BE):]
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if enc := lookupEncoding(params["charset"]); enc != nil {
+			return enc, body
+		}
+	}
+	head := body
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if m := prologEncodingRe.FindSubmatch(head); m != nil {
+		return lookupEncoding(string(m[3])), body
+	}
+	return nil, body
+}
+
+// Функция поиска кодировки по названию (windows-1251, cp1251, koi8-r и т.д.)
+func lookupEncoding(label string) encoding.Encoding {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label == "" {
+		return nil
+	}
+	if label == "cp1251" {
+		label = "windows-1251"
+	}
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil
+	}
+	return enc
+}
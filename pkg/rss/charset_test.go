@@ -0,0 +1,54 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// Тест проверяет перекодирование лент в устаревших кодировках по BOM, заголовку Content-Type и XML-прологу
+func TestParse_Charset(t *testing.T) {
+	const wantTitle = "Новости Go: релиз 1.23"
+	const wantContent = `Вышел релиз Go с итераторами - "ёлки-палки", ЁЖ и щука.`
+
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+	}{
+		{name: "windows-1251 in prolog", fixture: "windows-1251.xml", contentType: "application/rss+xml"},
+		{name: "KOI8-R in prolog", fixture: "koi8-r.xml", contentType: "text/xml"},
+		{name: "UTF-8 with BOM", fixture: "utf-8-bom.xml", contentType: "application/xml"},
+		{name: "UTF-16LE with BOM", fixture: "utf-16le-bom.xml", contentType: "application/xml"},
+		{name: "windows-1251 in header only", fixture: "header-only-cp1251.xml", contentType: "text/xml; charset=windows-1251"},
+		{name: "Header overrides prolog", fixture: "windows-1251.xml", contentType: "text/xml; charset=cp1251"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile("testdata/charset/" + tt.fixture)
+			if err != nil {
+				t.Fatalf("Error reading fixture - %v", err)
+			}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(data)
+			}))
+			defer srv.Close()
+
+			news, err := Parse(srv.URL)
+			if err != nil {
+				t.Fatalf("Parse() error - %v", err)
+			}
+			if len(news) != 1 {
+				t.Fatalf("Parse() returned %d items, want 1", len(news))
+			}
+			if news[0].Title != wantTitle {
+				t.Errorf("Title = %q, want %q", news[0].Title, wantTitle)
+			}
+			if news[0].Content != wantContent {
+				t.Errorf("Content = %q, want %q", news[0].Content, wantContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package rss
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Правила, по которым определена дата публикации статьи (поле PublishedSource)
+const (
+	DateFromPublished = "published" //дата публикации, разобранная gofeed
+	DateFromUpdated   = "updated"   //дата обновления, разобранная gofeed
+	DateFromLayout    = "layout"    //дата из строки одного из известных форматов
+	DateFromFetchTime = "fetch_time"
+	DateClamped       = "clamped" //дата из будущего, замененная временем загрузки
+)
+
+// Допустимое расхождение часов источника: даты не дальше этого интервала в будущем не считаются ошибочными
+const maxClockSkew = 10 * time.Minute
+
+// Известные форматы дат (после нормализации строки функцией normalizeDate)
+var dateLayouts = []string{
+	"Mon 2 Jan 2006 15:04:05 -0700",
+	"Mon 2 Jan 2006 15:04:05 MST",
+	"Mon 2 Jan 2006 15:04 -0700",
+	"Mon 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04",
+	"2 Jan 2006",
+	"Jan 2 2006 15:04:05",
+	"Jan 2 2006",
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// Русские названия месяцев (в любом падеже и сокращенные) и соответствующие им английские
+var ruMonths = map[string]string{
+	"января": "Jan", "январь": "Jan", "янв": "Jan",
+	"февраля": "Feb", "февраль": "Feb", "фев": "Feb",
+	"марта": "Mar", "март": "Mar", "мар": "Mar",
+	"апреля": "Apr", "апрель": "Apr", "апр": "Apr",
+	"мая": "May", "май": "May",
+	"июня": "Jun", "июнь": "Jun", "июн": "Jun",
+	"июля": "Jul", "июль": "Jul", "июл": "Jul",
+	"августа": "Aug", "август": "Aug", "авг": "Aug",
+	"сентября": "Sep", "сентябрь": "Sep", "сен": "Sep", "сент": "Sep",
+	"октября": "Oct", "октябрь": "Oct", "окт": "Oct",
+	"ноября": "Nov", "ноябрь": "Nov", "ноя": "Nov",
+	"декабря": "Dec", "декабрь": "Dec", "дек": "Dec",
+}
+
+// Русские названия дней недели, которые удаляются из строки даты
+var ruWeekdays = map[string]bool{
+	"пн": true, "вт": true, "ср": true, "чт": true, "пт": true, "сб": true, "вс": true,
+	"понедельник": true, "вторник": true, "среда": true, "четверг": true, "пятница": true, "суббота": true, "воскресенье": true,
+}
+
+// Функция определения даты публикации статьи. Порядок правил: дата публикации и дата обновления, разобранные gofeed,
+// затем известные форматы строк, затем время загрузки ленты. Даты из будущего заменяются временем загрузки.
+// Возвращает Unix-время и правило, по которому оно получено.
+func parsePublished(item *gofeed.Item, fetched time.Time) (int64, string) {
+	t, rule := itemDate(item)
+	return CheckDate(t, rule, fetched)
+}
+
+// Функция проверки даты публикации t, определенной по правилу rule, относительно времени загрузки fetched.
+// Пустое правило (дата не найдена) заменяется временем загрузки, даты из будущего - тоже.
+func CheckDate(t time.Time, rule string, fetched time.Time) (int64, string) {
+	if rule == "" {
+		return fetched.Unix(), DateFromFetchTime
+	}
+	if t.After(fetched.Add(maxClockSkew)) {
+		return fetched.Unix(), DateClamped
+	}
+	return t.Unix(), rule
+}
+
+// Функция возвращает дату статьи и правило, по которому она определена, или пустое правило, если даты нет
+func itemDate(item *gofeed.Item) (time.Time, string) {
+	if item.PublishedParsed != nil && !item.PublishedParsed.IsZero() {
+		return *item.PublishedParsed, DateFromPublished
+	}
+	if item.UpdatedParsed != nil && !item.UpdatedParsed.IsZero() {
+		return *item.UpdatedParsed, DateFromUpdated
+	}
+	for _, s := range []string{item.Published, item.Updated} {
+		if t, ok := ParseDate(s); ok {
+			return t, DateFromLayout
+		}
+	}
+	return time.Time{}, ""
+}
+
+// Функция разбора строки даты по списку известных форматов. Понимает русские названия месяцев и дней недели.
+func ParseDate(s string) (time.Time, bool) {
+	s = normalizeDate(s)
+	if s == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Функция нормализации строки даты: удаляет запятые и русские дни недели, переводит русские месяцы на английский
+func normalizeDate(s string) string {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	out := fields[:0]
+	for _, f := range fields {
+		word := strings.ToLower(strings.TrimSuffix(f, "."))
+		if ruWeekdays[word] {
+			continue
+		}
+		if m, ok := ruMonths[word]; ok {
+			f = m
+		}
+		out = append(out, f)
+	}
+	return strings.Join(out, " ")
+}
@@ -0,0 +1,147 @@
+package rss
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+// Максимальный размер загружаемой при поиске лент страницы
+const maxDiscoverySize = 5 << 20
+
+// Типы лент, объявляемых в <link rel="alternate">
+var feedMIMETypes = map[string]string{
+	"application/rss+xml":   "rss",
+	"application/atom+xml":  "atom",
+	"application/feed+json": "json",
+	"application/json":      "json",
+	"application/rdf+xml":   "rss",
+	"application/xml":       "rss",
+	"text/xml":              "rss",
+}
+
+// Типичные пути лент, которые проверяются, если страница не объявляет ленты явно
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json", "/feed/rss"}
+
+// Найденная лента
+type Candidate struct {
+	URL   string `json:"url"`
+	Title string `json:"title"`
+	Type  string `json:"type"` //rss, atom или json
+}
+
+// Функция поиска лент по адресу сайта. Если адрес сам указывает на ленту, возвращается она. Иначе ищутся ленты,
+// объявленные на странице в <link rel="alternate">, а если их нет - проверяются типичные пути лент на сайте.
+// Запросы выполняются загрузчиком f, например с настройками HTTP-клиента источника.
+func Discover(ctx context.Context, f fetcher.Fetcher, pageURL string) ([]Candidate, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	body, err := get(ctx, f, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := feedCandidate(pageURL, body); ok {
+		return []Candidate{c}, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var candidates []Candidate
+	seen := make(map[string]bool)
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !hasToken(rel, "alternate") {
+			return
+		}
+		typ, _ := s.Attr("type")
+		kind, ok := feedMIMETypes[strings.ToLower(strings.TrimSpace(typ))]
+		if !ok {
+			return
+		}
+		href, _ := s.Attr("href")
+		ref, err := base.Parse(strings.TrimSpace(href))
+		//источником может быть только лента, доступная по http(s)
+		if err != nil || (ref.Scheme != "http" && ref.Scheme != "https") || seen[ref.String()] {
+			return
+		}
+		seen[ref.String()] = true
+		title, _ := s.Attr("title")
+		candidates = append(candidates, Candidate{URL: ref.String(), Title: strings.TrimSpace(title), Type: kind})
+	})
+	if len(candidates) > 0 {
+		return candidates, nil
+	}
+
+	for _, path := range commonFeedPaths {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		ref := base.ResolveReference(&url.URL{Path: path}).String()
+		body, err := get(ctx, f, ref)
+		if err != nil {
+			continue
+		}
+		if c, ok := feedCandidate(ref, body); ok && !seen[c.URL] {
+			seen[c.URL] = true
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, nil
+}
+
+// Функция проверяет, является ли документ лентой, и возвращает ее описание
+func feedCandidate(feedURL string, body []byte) (Candidate, bool) {
+	var kind string
+	switch gofeed.DetectFeedType(bytes.NewReader(body)) {
+	case gofeed.FeedTypeRSS:
+		kind = "rss"
+	case gofeed.FeedTypeAtom:
+		kind = "atom"
+	case gofeed.FeedTypeJSON:
+		kind = "json"
+	default:
+		return Candidate{}, false
+	}
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return Candidate{}, false
+	}
+	return Candidate{URL: feedURL, Title: feed.Title, Type: kind}, true
+}
+
+// Функция загрузки документа по адресу
+func get(ctx context.Context, f fetcher.Fetcher, link string) ([]byte, error) {
+	resp, err := f.Fetch(ctx, link)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d for %s", resp.StatusCode, link)
+	}
+	if len(resp.Body) > maxDiscoverySize {
+		return resp.Body[:maxDiscoverySize], nil
+	}
+	return resp.Body, nil
+}
+
+// Функция проверяет, содержит ли список через пробел заданное значение (без учета регистра)
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
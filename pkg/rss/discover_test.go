@@ -0,0 +1,81 @@
+package rss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestDiscover(t *testing.T) {
+	const rssFeed = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Go hub</title></channel></rss>`
+	const atomFeed = `<?xml version="1.0" encoding="utf-8"?><feed xmlns="http://www.w3.org/2005/Atom"><title>Site atom</title></feed>`
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ru/hub/go", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+		<link rel="alternate" type="application/rss+xml" title="Go hub" href="/ru/rss/hub/go/">
+		<link rel="alternate" type="application/rss+xml" title="Go hub duplicate" href="/ru/rss/hub/go/">
+		<link rel="stylesheet" type="text/css" href="/style.css">
+		<link rel="alternate" hreflang="en" href="/en/hub/go">
+		</head><body></body></html>`)
+	})
+	mux.HandleFunc("/schemes", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head>
+		<link rel="alternate" type="application/rss+xml" title="File" href="file:///etc/passwd">
+		<link rel="alternate" type="application/rss+xml" title="Script" href="javascript:alert(1)">
+		<link rel="alternate" type="application/atom+xml" title="Atom" href="/atom.xml">
+		</head><body></body></html>`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>No feeds declared</title></head></html>`)
+	})
+	mux.HandleFunc("/ru/rss/hub/go/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, rssFeed)
+	})
+	mux.HandleFunc("/atom.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, atomFeed)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		url  string
+		want []Candidate
+	}{
+		{
+			name: "Feeds declared in link tags",
+			url:  srv.URL + "/ru/hub/go",
+			want: []Candidate{{URL: srv.URL + "/ru/rss/hub/go/", Title: "Go hub", Type: "rss"}},
+		},
+		{
+			name: "Non-http links skipped",
+			url:  srv.URL + "/schemes",
+			want: []Candidate{{URL: srv.URL + "/atom.xml", Title: "Atom", Type: "atom"}},
+		},
+		{
+			name: "Common feed path",
+			url:  srv.URL + "/plain",
+			want: []Candidate{{URL: srv.URL + "/atom.xml", Title: "Site atom", Type: "atom"}},
+		},
+		{
+			name: "URL is a feed itself",
+			url:  srv.URL + "/ru/rss/hub/go/",
+			want: []Candidate{{URL: srv.URL + "/ru/rss/hub/go/", Title: "Go hub", Type: "rss"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Discover(context.Background(), NewFetcher(), tt.url)
+			if err != nil {
+				t.Fatalf("Discover() error - %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Discover() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
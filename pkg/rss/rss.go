@@ -1,28 +1,123 @@
 package rss
 
 import (
+	"bytes"
+	"context"
 	"log"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/sanitize"
 	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"Skillfactory/36-GoNews/pkg/websub"
 
 	strip "github.com/grokify/html-strip-tags-go"
 	"github.com/mmcdole/gofeed"
 )
 
+// User-Agent, с которым выполняются запросы к источникам
+const userAgent = "Gofeed/1.0"
+
+var (
+	client = &http.Client{Timeout: 30 * time.Second}
+	//загрузчик лент по умолчанию: http(s):// с условными запросами
+	DefaultFetcher = NewFetcher()
+	//источник текущего времени, подменяется в тестах
+	now = time.Now
+)
+
+// Конструктор загрузчика лент http(s):// со своим кэшем валидаторов условных запросов
+func NewFetcher() fetcher.Fetcher {
+	return fetcher.New(client, userAgent)
+}
+
+// Конструктор загрузчика с настройками HTTP-клиента источника (авторизация, заголовки, прокси, TLS)
+func NewSourceFetcher(opts *models.HTTPOptions) (fetcher.Fetcher, error) {
+	return fetcher.NewWithOptions(client, userAgent, opts)
+}
+
 // Метод - парсер источника RSS. На вход получается строку с URL источника, вовращает слайс объектов или ошибку.
+// Повторные запросы к источнику выполняются условно (If-None-Match / If-Modified-Since): если лента не изменилась
+// и источник ответил 304, метод возвращает пустой слайс без ошибки. Ленты в устаревших кодировках
+// (windows-1251, KOI8-R и т.д.) перекодируются в UTF-8 перед разбором.
 func Parse(source string) ([]models.NewsFullDetailed, error) {
-	parser := gofeed.NewParser()
-	var news []models.NewsFullDetailed
-	var new models.NewsFullDetailed
-	feed, err := parser.ParseURL(source)
+	return ParseWithContext(context.Background(), source)
+}
+
+// Метод - парсер источника RSS, загрузка которого прерывается при отмене контекста.
+func ParseWithContext(ctx context.Context, source string) ([]models.NewsFullDetailed, error) {
+	res, err := Fetch(ctx, source)
+	return res.News, err
+}
+
+// Результат загрузки ленты
+type Result struct {
+	News       []models.NewsFullDetailed
+	StatusCode int    //код ответа источника (304 - лента не изменилась)
+	Hub        string //хаб WebSub, указанный в ленте (rel="hub")
+	Self       string //URL ленты, указанный в ней самой (rel="self")
+}
+
+// Метод загрузки и разбора ленты загрузчиком по умолчанию с кодом ответа источника. При ответе вне диапазона
+// 2xx/304 возвращает ошибку fetcher.HTTPError с кодом ответа.
+func Fetch(ctx context.Context, source string) (Result, error) {
+	return FetchWith(ctx, DefaultFetcher, source)
+}
+
+// Метод загрузки ленты указанным загрузчиком и ее разбора.
+func FetchWith(ctx context.Context, f fetcher.Fetcher, source string) (Result, error) {
+	resp, err := f.Fetch(ctx, source)
+	res := Result{StatusCode: resp.StatusCode}
+	if err != nil {
+		log.Printf("Parsing error - %v", err)
+		return res, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return res, nil
+	}
+
+	body, err := toUTF8(resp.Body, resp.ContentType)
 	if err != nil {
 		log.Printf("Parsing error - %v", err)
+		return res, err
+	}
+	res.News, err = parseFeed(body)
+	if err != nil {
+		log.Printf("Parsing error - %v", err)
+		return res, err
+	}
+	//валидаторы запоминаются только после успешного разбора ленты, иначе сломанная лента "застрянет" в 304
+	if c, ok := f.(fetcher.Conditional); ok {
+		c.Remember(source, resp)
+	}
+	res.Hub, res.Self = websub.FindHub(body)
+	return res, nil
+}
+
+// Метод разбора уже загруженной ленты, например доставленной хабом WebSub. contentType - значение заголовка
+// Content-Type, используется для определения кодировки.
+func ParseFeed(body []byte, contentType string) ([]models.NewsFullDetailed, error) {
+	body, err := toUTF8(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return parseFeed(body)
+}
+
+// Разбор ленты в UTF-8
+func parseFeed(body []byte) ([]models.NewsFullDetailed, error) {
+	fetched := now()
+	parser := gofeed.NewParser()
+	feed, err := parser.Parse(bytes.NewReader(body))
+	if err != nil {
 		return nil, err
 	}
+	var news []models.NewsFullDetailed
 	for _, item := range feed.Items {
-		new, err = FeedItemToNews(item)
+		new, err := feedItemToNews(item, fetched)
 		if err != nil {
 			log.Println(err)
 		}
@@ -33,24 +128,74 @@ func Parse(source string) ([]models.NewsFullDetailed, error) {
 
 // Метод - конвертер объекта gofeed.Item, предоставляемаого библиотекой gofeed (объект статьи после парсинга XML),
 // в объект статьи models.NewsFullDetailed. Возращает ошибку при наличии.
+// Статьи без даты публикации получают текущее время.
 func FeedItemToNews(item *gofeed.Item) (news models.NewsFullDetailed, err error) {
-	published := strings.ReplaceAll(item.Published, ",", "")
-	t, err := time.Parse("Mon 2 Jan 2006 15:04:05 -0700", published)
-	if err != nil {
-		t, err = time.Parse("Mon 2 Jan 2006 15:04:05 GMT", published)
-	}
-	if err == nil {
-		news.Published = t.Unix()
-	}
+	return feedItemToNews(item, now())
+}
+
+// Конвертер статьи ленты, загруженной в момент fetched
+func feedItemToNews(item *gofeed.Item, fetched time.Time) (news models.NewsFullDetailed, err error) {
+	news.Published, news.PublishedSource = parsePublished(item, fetched)
 
-	news.Content = item.Description
-	news.Content = strip.StripTags(news.Content)
+	description := item.Description
+	if description == "" {
+		description = item.Content
+	}
+	news.ContentHTML = sanitize.HTML(description)
+	news.Content = strip.StripTags(description)
 
 	news = models.NewsFullDetailed{
-		Title:     item.Title,
-		Content:   news.Content,
-		Published: news.Published,
-		Link:      item.Link,
+		Title:           item.Title,
+		Content:         news.Content,
+		ContentHTML:     news.ContentHTML,
+		Published:       news.Published,
+		Link:            item.Link,
+		PublishedSource: news.PublishedSource,
+		Author:          itemAuthor(item),
+		Categories:      item.Categories,
+		GUID:            item.GUID,
+		Enclosures:      itemEnclosures(item),
 	}
+	news.ImageURL = itemImage(item, news.Enclosures)
 	return news, nil
 }
+
+// Функция возвращает авторов статьи через запятую
+func itemAuthor(item *gofeed.Item) string {
+	var names []string
+	for _, a := range item.Authors {
+		if a != nil && a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	if len(names) == 0 && item.Author != nil {
+		return item.Author.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// Функция конвертации вложений статьи
+func itemEnclosures(item *gofeed.Item) []models.Enclosure {
+	var enclosures []models.Enclosure
+	for _, e := range item.Enclosures {
+		if e == nil || e.URL == "" {
+			continue
+		}
+		length, _ := strconv.ParseInt(e.Length, 10, 64)
+		enclosures = append(enclosures, models.Enclosure{URL: e.URL, Type: e.Type, Length: length})
+	}
+	return enclosures
+}
+
+// Функция возвращает URL изображения статьи. Если у статьи нет изображения, используется первое вложение-картинка.
+func itemImage(item *gofeed.Item, enclosures []models.Enclosure) string {
+	if item.Image != nil && item.Image.URL != "" {
+		return item.Image.URL
+	}
+	for _, e := range enclosures {
+		if strings.HasPrefix(e.Type, "image/") {
+			return e.URL
+		}
+	}
+	return ""
+}
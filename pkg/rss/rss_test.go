@@ -1,38 +1,197 @@
 package rss
 
 import (
+	"Skillfactory/36-GoNews/pkg/fetcher"
 	models "Skillfactory/36-GoNews/pkg/storage/models"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/mmcdole/gofeed"
 )
 
+// Тест разбирает ленты из testdata/feeds - сохраненные копии реальных лент разных форматов
 func TestParse(t *testing.T) {
-	validSource := "https://habr.com/ru/rss/hub/go/all/?fl=ru"
-	parser := gofeed.NewParser()
-	feed, err := parser.ParseURL(validSource)
+	tests := []struct {
+		name  string
+		file  string
+		count int
+		first models.NewsFullDetailed
+	}{
+		{
+			name:  "RSS 2.0 with CDATA",
+			file:  "habr.xml",
+			count: 3,
+			first: models.NewsFullDetailed{
+				Title:      "Горутины под капотом: как работает планировщик Go",
+				Link:       "https://habr.com/ru/articles/854102/?utm_source=habrahabr&utm_medium=rss&utm_campaign=854102",
+				Author:     "gopher_dev",
+				Published:  1730200840,
+				GUID:       "https://habr.com/ru/articles/854102/",
+				Categories: []string{"Go", "Высокая производительность", "golang"},
+				ImageURL:   "https://habrastorage.org/getpro/habr/upload_files/3a1/b2c/d4e/3a1b2cd4e.png",
+			},
+		},
+		{
+			name:  "Atom",
+			file:  "goblog.atom",
+			count: 2,
+			first: models.NewsFullDetailed{
+				Title:     "Go 1.23 is released",
+				Link:      "https://go.dev/blog/go1.23",
+				Author:    "Go Team",
+				Published: 1723507200,
+				GUID:      "tag:blog.golang.org,2013:blog.golang.org/go1.23",
+			},
+		},
+		{
+			name:  "RSS 1.0 in ISO-8859-1",
+			file:  "rdf.xml",
+			count: 1,
+			first: models.NewsFullDetailed{
+				Title:      "Go Turns Fifteen",
+				Link:       "https://developers.slashdot.org/story/24/10/29/0412233/go-turns-fifteen?utm_source=rss1.0mainlinkanon&utm_medium=feed",
+				Author:     "msmash",
+				Published:  1730175120,
+				Categories: []string{"programming"},
+			},
+		},
+		{
+			name:  "Podcast",
+			file:  "podcast.xml",
+			count: 1,
+			first: models.NewsFullDetailed{
+				Title:     "Go 1.23 and iterators",
+				Link:      "https://changelog.com/gotime/326",
+				Author:    "Changelog Media",
+				Published: 1723665600,
+				GUID:      "changelog.com/2/2568",
+				ImageURL:  "https://cdn.changelog.com/uploads/gotime/326/go-time-326.png",
+			},
+		},
+		{
+			name:  "Image enclosures",
+			file:  "media.xml",
+			count: 2,
+			first: models.NewsFullDetailed{
+				Title:      "Российские ученые представили 50-кубитный квантовый компьютер",
+				Link:       "https://lenta.ru/news/2024/10/29/kvantovyy-kompyuter/",
+				Author:     "Анна Иванова",
+				Published:  1730201820,
+				GUID:       "https://lenta.ru/news/2024/10/29/kvantovyy-kompyuter/",
+				Categories: []string{"Наука и техника"},
+				ImageURL:   "https://icdn.lenta.ru/images/2024/10/29/14/20241029143700123/pic_1.jpg",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := filepath.Abs(filepath.Join("testdata", "feeds", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := FetchWith(context.Background(), fetcher.Mux{"file": fetcher.File{}}, "file://"+filepath.ToSlash(path))
+			news := res.News
+			if err != nil {
+				t.Fatalf("Error parsing feed - %v", err)
+			}
+			if len(news) != tt.count {
+				t.Fatalf("Parse() returned %d items, want %d", len(news), tt.count)
+			}
+			got := news[0]
+			if got.Title != tt.first.Title || got.Link != tt.first.Link || got.Author != tt.first.Author ||
+				got.Published != tt.first.Published || got.GUID != tt.first.GUID || got.ImageURL != tt.first.ImageURL ||
+				!reflect.DeepEqual(got.Categories, tt.first.Categories) {
+				t.Errorf("Parse() first item = %+v, want %+v", got, tt.first)
+			}
+			for _, n := range news {
+				if n.Title == "" || n.Link == "" || n.Content == "" {
+					t.Errorf("Parse() returned incomplete item %+v", n)
+				}
+			}
+		})
+	}
+}
+
+// Тест проверяет разбор ленты, загруженной из памяти, и ошибку для отсутствующего документа
+func TestFetchWith_Memory(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "feeds", "goblog.atom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := fetcher.Memory{"mem://goblog": body}
 
+	res, err := FetchWith(context.Background(), f, "mem://goblog")
 	if err != nil {
-		t.Fatalf("Error parsing URL to feed - %v", err)
-	}
-	var new models.NewsFullDetailed
-	var news []models.NewsFullDetailed
-	for _, item := range feed.Items {
-		new, err = FeedItemToNews(item)
-		if err != nil {
-			log.Println(err)
+		t.Fatalf("Error parsing feed - %v", err)
+	}
+	if len(res.News) != 2 || res.StatusCode != http.StatusOK {
+		t.Errorf("FetchWith() returned %d items with status %d, want 2 and 200", len(res.News), res.StatusCode)
+	}
+
+	_, err = FetchWith(context.Background(), f, "mem://unknown")
+	if !errors.Is(err, fetcher.ErrNotFound) {
+		t.Errorf("FetchWith() error = %v, want %v", err, fetcher.ErrNotFound)
+	}
+}
+
+// Тест проверяет, что при повторном запросе отправляются сохраненные ETag/Last-Modified,
+// а ответ 304 трактуется как отсутствие новых статей
+func TestParse_NotModified(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Tue, 29 Oct 2024 11:20:40 GMT"
+	feed := `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"><channel><title>Test</title>
+	<item><title>Test Title 1</title><link>https://example.com/1</link><description>Test Description 1</description></item>
+	</channel></rss>`
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
-		news = append(news, new)
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		fmt.Fprint(w, feed)
+	}))
+	defer srv.Close()
+
+	news, err := Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("Error parsing feed - %v", err)
 	}
-	if len(news) < 1 {
-		t.Fatalf("Error parsing feed to items - %v", err)
+	if len(news) != 1 {
+		t.Fatalf("Parse() returned %d items, want 1", len(news))
 	}
 
+	news, err = Parse(srv.URL)
+	if err != nil {
+		t.Fatalf("Error parsing not modified feed - %v", err)
+	}
+	if len(news) != 0 {
+		t.Errorf("Parse() returned %d items for 304 response, want 0", len(news))
+	}
+	if requests != 2 {
+		t.Errorf("server got %d requests, want 2", requests)
+	}
 }
 
 func TestFeedItemToNews(t *testing.T) {
+	fetched := time.Date(2024, 10, 30, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fetched }
+	defer func() { now = time.Now }()
+
+	published := time.Date(2024, 10, 29, 11, 20, 40, 0, time.UTC)
+	future := fetched.Add(48 * time.Hour)
+
 	type args struct {
 		item *gofeed.Item
 	}
@@ -55,10 +214,110 @@ func TestFeedItemToNews(t *testing.T) {
 			},
 
 			want: models.NewsFullDetailed{
-				Title:     "Test Title 1",
-				Content:   "Test Description 1",
-				Published: 1730200840,
-				Link:      "https://github.com/mmcdole/gofeed/blob/v1.3.0/parser.go#L96",
+				Title:           "Test Title 1",
+				Content:         "Test Description 1",
+				ContentHTML:     "Test Description 1",
+				Published:       1730200840,
+				Link:            "https://github.com/mmcdole/gofeed/blob/v1.3.0/parser.go#L96",
+				PublishedSource: DateFromLayout,
+			},
+		},
+
+		{
+			name: "Date parsed by gofeed",
+
+			args: args{
+
+				item: &gofeed.Item{
+					Title:           "Test Title 3",
+					Published:       "2024-10-29T11:20:40Z",
+					PublishedParsed: &published,
+					Link:            "https://example.com/3",
+				},
+			},
+
+			want: models.NewsFullDetailed{
+				Title:           "Test Title 3",
+				Published:       1730200840,
+				Link:            "https://example.com/3",
+				PublishedSource: DateFromPublished,
+			},
+		},
+
+		{
+			name: "Only updated date",
+
+			args: args{
+
+				item: &gofeed.Item{
+					Title:         "Test Title 4",
+					UpdatedParsed: &published,
+					Link:          "https://example.com/4",
+				},
+			},
+
+			want: models.NewsFullDetailed{
+				Title:           "Test Title 4",
+				Published:       1730200840,
+				Link:            "https://example.com/4",
+				PublishedSource: DateFromUpdated,
+			},
+		},
+
+		{
+			name: "Future date is clamped",
+
+			args: args{
+
+				item: &gofeed.Item{
+					Title:           "Test Title 5",
+					PublishedParsed: &future,
+					Link:            "https://example.com/5",
+				},
+			},
+
+			want: models.NewsFullDetailed{
+				Title:           "Test Title 5",
+				Published:       fetched.Unix(),
+				Link:            "https://example.com/5",
+				PublishedSource: DateClamped,
+			},
+		},
+
+		{
+			name: "Author, categories, GUID and enclosures",
+
+			args: args{
+
+				item: &gofeed.Item{
+					Title:       "Test Title 2",
+					Description: "<p>Test Description 2</p>",
+					Link:        "https://example.com/2",
+					Authors:     []*gofeed.Person{{Name: "Ivan"}, {Name: "Petr"}},
+					Categories:  []string{"Go", "Backend"},
+					GUID:        "https://example.com/?p=2",
+					Enclosures: []*gofeed.Enclosure{
+						{URL: "https://example.com/2.mp3", Type: "audio/mpeg", Length: "1024"},
+						{URL: "https://example.com/2.png", Type: "image/png"},
+					},
+				},
+			},
+
+			want: models.NewsFullDetailed{
+				Title:       "Test Title 2",
+				Content:     "Test Description 2",
+				ContentHTML: "<p>Test Description 2</p>",
+				Link:        "https://example.com/2",
+				Author:      "Ivan, Petr",
+				Categories:  []string{"Go", "Backend"},
+				GUID:        "https://example.com/?p=2",
+				Enclosures: []models.Enclosure{
+					{URL: "https://example.com/2.mp3", Type: "audio/mpeg", Length: 1024},
+					{URL: "https://example.com/2.png", Type: "image/png"},
+				},
+				ImageURL:        "https://example.com/2.png",
+				Published:       fetched.Unix(),
+				PublishedSource: DateFromFetchTime,
 			},
 		},
 
@@ -76,10 +335,11 @@ func TestFeedItemToNews(t *testing.T) {
 			},
 
 			want: models.NewsFullDetailed{
-				Title:     "",
-				Content:   "",
-				Published: 0,
-				Link:      "",
+				Title:           "",
+				Content:         "",
+				Published:       fetched.Unix(),
+				Link:            "",
+				PublishedSource: DateFromFetchTime,
 			},
 		},
 	}
@@ -92,3 +352,36 @@ func TestFeedItemToNews(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 10, 29, 11, 20, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+		ok    bool
+	}{
+		{name: "RFC1123Z", input: "Tue, 29 Oct 2024 11:20:00 +0000", want: want, ok: true},
+		{name: "RFC1123 GMT", input: "Tue, 29 Oct 2024 11:20:00 GMT", want: want, ok: true},
+		{name: "RFC3339", input: "2024-10-29T11:20:00Z", want: want, ok: true},
+		{name: "RFC3339 with offset", input: "2024-10-29T14:20:00+03:00", want: want, ok: true},
+		{name: "W3C without seconds", input: "2024-10-29T14:20+03:00", want: want, ok: true},
+		{name: "Without weekday", input: "29 Oct 2024 11:20:00 +0000", want: want, ok: true},
+		{name: "Russian month", input: "29 октября 2024, 11:20", want: want, ok: true},
+		{name: "Russian weekday and short month", input: "Вт, 29 окт. 2024 11:20:00 +0000", want: want, ok: true},
+		{name: "Dotted date", input: "29.10.2024 11:20", want: want, ok: true},
+		{name: "Empty string", input: "", ok: false},
+		{name: "Garbage", input: "yesterday", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseDate(tt.input)
+			if ok != tt.ok {
+				t.Fatalf("ParseDate(%q) ok = %v, want %v", tt.input, ok, tt.ok)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("ParseDate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
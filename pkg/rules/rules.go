@@ -0,0 +1,188 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"Skillfactory/36-GoNews/pkg/sanitize"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Действия правил
+const (
+	Include = "include"
+	Exclude = "exclude"
+	Rewrite = "rewrite"
+)
+
+// Поля статьи, к которым применяются правила
+const (
+	FieldTitle    = "title"
+	FieldContent  = "content"
+	FieldCategory = "category"
+	FieldAuthor   = "author"
+)
+
+// Скомпилированный набор правил источника
+type Program struct {
+	rules []rule
+}
+
+type rule struct {
+	models.Rule
+	n       int            //номер правила в исходном списке, начиная с 1
+	re      *regexp.Regexp //nil для правил с ключевым словом
+	keyword *regexp.Regexp //ключевое слово без учета регистра для правил rewrite
+}
+
+// Отброшенная правилами статья
+type Dropped struct {
+	News   models.NewsFullDetailed
+	Rule   int    //номер правила, начиная с 1; 0 - статья не подошла ни под одно правило include
+	Reason string //описание правила
+}
+
+// Результат применения правил
+type Result struct {
+	Kept    []models.NewsFullDetailed
+	Dropped []Dropped
+}
+
+// Функция проверки и компиляции правил источника
+func Compile(rules []models.Rule) (Program, error) {
+	var p Program
+	for i, r := range rules {
+		c := rule{Rule: r, n: i + 1}
+		switch r.Action {
+		case Include, Exclude, Rewrite:
+		default:
+			return Program{}, fmt.Errorf("rule %d: unknown action %q", c.n, r.Action)
+		}
+		switch r.Field {
+		case FieldTitle, FieldContent, FieldCategory, FieldAuthor:
+		default:
+			return Program{}, fmt.Errorf("rule %d: unknown field %q", c.n, r.Field)
+		}
+		if (r.Match == "") == (r.Regex == "") {
+			return Program{}, fmt.Errorf("rule %d: exactly one of match and regex must be set", c.n)
+		}
+		if r.Regex != "" {
+			re, err := regexp.Compile(r.Regex)
+			if err != nil {
+				return Program{}, fmt.Errorf("rule %d: %w", c.n, err)
+			}
+			c.re = re
+		} else if r.Action == Rewrite {
+			c.keyword = regexp.MustCompile("(?i)" + regexp.QuoteMeta(r.Match))
+		}
+		p.rules = append(p.rules, c)
+	}
+	return p, nil
+}
+
+// Метод применения правил к статьям. Сначала по порядку выполняются все правила rewrite, затем статьи
+// отбираются: статья отбрасывается, если подходит под правило exclude или если правила include есть,
+// но статья не подходит ни под одно из них.
+func (p Program) Apply(news []models.NewsFullDetailed) Result {
+	var res Result
+	for _, n := range news {
+		for _, r := range p.rules {
+			if r.Action == Rewrite {
+				r.rewrite(&n)
+			}
+		}
+		dropped, ok := p.filter(n)
+		if ok {
+			res.Kept = append(res.Kept, n)
+		} else {
+			res.Dropped = append(res.Dropped, dropped)
+		}
+	}
+	return res
+}
+
+// Отбор статьи правилами include/exclude
+func (p Program) filter(n models.NewsFullDetailed) (Dropped, bool) {
+	hasInclude, included := false, false
+	for _, r := range p.rules {
+		switch r.Action {
+		case Exclude:
+			if r.matches(n) {
+				return Dropped{News: n, Rule: r.n, Reason: r.String()}, false
+			}
+		case Include:
+			hasInclude = true
+			included = included || r.matches(n)
+		}
+	}
+	if hasInclude && !included {
+		return Dropped{News: n, Reason: "no include rule matched"}, false
+	}
+	return Dropped{}, true
+}
+
+func (r rule) matches(n models.NewsFullDetailed) bool {
+	for _, v := range r.values(n) {
+		if r.re != nil && r.re.MatchString(v) {
+			return true
+		}
+		if r.re == nil && strings.Contains(strings.ToLower(v), strings.ToLower(r.Match)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Значения поля статьи, к которым применяется правило
+func (r rule) values(n models.NewsFullDetailed) []string {
+	switch r.Field {
+	case FieldTitle:
+		return []string{n.Title}
+	case FieldContent:
+		return []string{n.Content, n.ContentHTML}
+	case FieldCategory:
+		return n.Categories
+	case FieldAuthor:
+		return []string{n.Author}
+	}
+	return nil
+}
+
+// Замена в поле статьи. Ключевое слово, как и при отборе, ищется без учета регистра. После замены
+// в HTML статьи он очищается повторно: замена может вставить разметку, не прошедшую очистку.
+func (r rule) rewrite(n *models.NewsFullDetailed) {
+	replace := func(s string) string {
+		if r.re != nil {
+			return strings.TrimSpace(r.re.ReplaceAllString(s, r.Replace))
+		}
+		return strings.TrimSpace(r.keyword.ReplaceAllLiteralString(s, r.Replace))
+	}
+	switch r.Field {
+	case FieldTitle:
+		n.Title = replace(n.Title)
+	case FieldContent:
+		n.Content = replace(n.Content)
+		if n.ContentHTML != "" {
+			n.ContentHTML = sanitize.HTML(replace(n.ContentHTML))
+		}
+	case FieldCategory:
+		categories := make([]string, 0, len(n.Categories))
+		for _, c := range n.Categories {
+			if c = replace(c); c != "" {
+				categories = append(categories, c)
+			}
+		}
+		n.Categories = categories
+	case FieldAuthor:
+		n.Author = replace(n.Author)
+	}
+}
+
+// Описание правила для отчетов
+func (r rule) String() string {
+	if r.re != nil {
+		return fmt.Sprintf("%s %s =~ /%s/", r.Action, r.Field, r.Regex)
+	}
+	return fmt.Sprintf("%s %s contains %q", r.Action, r.Field, r.Match)
+}
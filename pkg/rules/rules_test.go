@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []models.Rule
+		wantErr bool
+	}{
+		{name: "Empty", rules: nil},
+		{name: "Valid", rules: []models.Rule{
+			{Action: Exclude, Field: FieldTitle, Match: "sponsor"},
+			{Action: Rewrite, Field: FieldTitle, Regex: `\s*\| Habr$`},
+		}},
+		{name: "Unknown action", rules: []models.Rule{{Action: "drop", Field: FieldTitle, Match: "x"}}, wantErr: true},
+		{name: "Unknown field", rules: []models.Rule{{Action: Exclude, Field: "link", Match: "x"}}, wantErr: true},
+		{name: "No pattern", rules: []models.Rule{{Action: Exclude, Field: FieldTitle}}, wantErr: true},
+		{name: "Both patterns", rules: []models.Rule{{Action: Exclude, Field: FieldTitle, Match: "x", Regex: "x"}}, wantErr: true},
+		{name: "Invalid regex", rules: []models.Rule{{Action: Exclude, Field: FieldTitle, Regex: "("}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Compile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProgram_Apply(t *testing.T) {
+	news := []models.NewsFullDetailed{
+		{Title: "Go 1.23 released | Golang Weekly", Content: "Iterators are here", Categories: []string{"Go"}},
+		{Title: "Sponsor: Try our cloud | Golang Weekly", Content: "Deploy in seconds", Categories: []string{"Go"}},
+		{Title: "Кулинарные рецепты", Content: "Борщ", Categories: []string{"Еда"}},
+		{Title: "Profiling tips", Content: "Use <b>pprof</b> in production", Categories: []string{"Go", "Performance"}},
+	}
+	tests := []struct {
+		name        string
+		rules       []models.Rule
+		wantKept    []string
+		wantDropped []int
+	}{
+		{
+			name:     "No rules",
+			wantKept: []string{news[0].Title, news[1].Title, news[2].Title, news[3].Title},
+		},
+		{
+			name: "Exclude by keyword ignores case",
+			rules: []models.Rule{
+				{Action: Exclude, Field: FieldTitle, Match: "SPONSOR"},
+			},
+			wantKept:    []string{news[0].Title, news[2].Title, news[3].Title},
+			wantDropped: []int{1},
+		},
+		{
+			name: "Include by category",
+			rules: []models.Rule{
+				{Action: Include, Field: FieldCategory, Match: "go"},
+			},
+			wantKept:    []string{news[0].Title, news[1].Title, news[3].Title},
+			wantDropped: []int{0},
+		},
+		{
+			name: "Exclude by content regex",
+			rules: []models.Rule{
+				{Action: Exclude, Field: FieldContent, Regex: `(?i)deploy|борщ`},
+			},
+			wantKept:    []string{news[0].Title, news[3].Title},
+			wantDropped: []int{1, 1},
+		},
+		{
+			name: "Rewrite runs before filters",
+			rules: []models.Rule{
+				{Action: Include, Field: FieldTitle, Regex: `^Go `},
+				{Action: Rewrite, Field: FieldTitle, Regex: `\s*\| Golang Weekly$`},
+				{Action: Exclude, Field: FieldTitle, Regex: `released$`},
+			},
+			wantDropped: []int{3, 0, 0, 0},
+		},
+		{
+			name: "Rewrite suffix",
+			rules: []models.Rule{
+				{Action: Rewrite, Field: FieldTitle, Match: "| Golang Weekly"},
+				{Action: Exclude, Field: FieldTitle, Regex: `^Sponsor:`},
+			},
+			wantKept:    []string{"Go 1.23 released", news[2].Title, news[3].Title},
+			wantDropped: []int{2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := p.Apply(news)
+			var kept []string
+			for _, n := range res.Kept {
+				kept = append(kept, n.Title)
+			}
+			var dropped []int
+			for _, d := range res.Dropped {
+				dropped = append(dropped, d.Rule)
+				if d.Reason == "" {
+					t.Errorf("dropped %q without reason", d.News.Title)
+				}
+			}
+			if !reflect.DeepEqual(kept, tt.wantKept) {
+				t.Errorf("Apply() kept = %q, want %q", kept, tt.wantKept)
+			}
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("Apply() dropped rules = %v, want %v", dropped, tt.wantDropped)
+			}
+		})
+	}
+	if news[0].Title != "Go 1.23 released | Golang Weekly" {
+		t.Errorf("Apply() modified input: %q", news[0].Title)
+	}
+}
+
+// Тест проверяет замену ключевого слова без учета регистра и повторную очистку HTML после замены
+func TestProgram_Rewrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     models.Rule
+		news     models.NewsFullDetailed
+		wantText string
+		wantHTML string
+	}{
+		{
+			name:     "Keyword ignores case",
+			rule:     models.Rule{Action: Rewrite, Field: FieldTitle, Match: "| golang weekly"},
+			news:     models.NewsFullDetailed{Title: "Go 1.23 released | Golang Weekly"},
+			wantText: "Go 1.23 released",
+		},
+		{
+			name:     "Keyword is not a pattern",
+			rule:     models.Rule{Action: Rewrite, Field: FieldTitle, Match: "a.b", Replace: "$1"},
+			news:     models.NewsFullDetailed{Title: "A.B and axb"},
+			wantText: "$1 and axb",
+		},
+		{
+			name: "Replaced HTML is sanitized",
+			rule: models.Rule{Action: Rewrite, Field: FieldContent, Match: "[ad]",
+				Replace: `<img src="x" onerror="alert(1)"><script>alert(2)</script>`},
+			news:     models.NewsFullDetailed{Content: "text [AD]", ContentHTML: "<p>text [AD]</p>"},
+			wantText: `text <img src="x" onerror="alert(1)"><script>alert(2)</script>`,
+			wantHTML: `<p>text <img src="x" /></p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile([]models.Rule{tt.rule})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := p.Apply([]models.NewsFullDetailed{tt.news})
+			if len(res.Kept) != 1 {
+				t.Fatalf("Apply() kept %d articles, want 1", len(res.Kept))
+			}
+			got := res.Kept[0]
+			text := got.Title
+			if tt.rule.Field == FieldContent {
+				text = got.Content
+			}
+			if text != tt.wantText || got.ContentHTML != tt.wantHTML {
+				t.Errorf("Apply() = %q, %q, want %q, %q", text, got.ContentHTML, tt.wantText, tt.wantHTML)
+			}
+		})
+	}
+}
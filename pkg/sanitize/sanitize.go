@@ -0,0 +1,165 @@
+package sanitize
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Разрешенные теги и их атрибуты. Все остальные теги удаляются, их текст сохраняется.
+var allowedTags = map[string][]string{
+	"a": {"href", "title"}, "img": {"src", "alt", "title", "width", "height"},
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil,
+	"pre": {"class"}, "code": {"class"}, "blockquote": nil, "kbd": nil, "samp": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"b": nil, "strong": nil, "i": nil, "em": nil, "u": nil, "s": nil, "del": nil, "ins": nil, "sub": nil, "sup": nil, "mark": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil, "th": {"colspan", "rowspan"}, "td": {"colspan", "rowspan"},
+	"figure": nil, "figcaption": nil,
+}
+
+// Теги, которые удаляются вместе с содержимым. Пустые элементы без закрывающего тега (embed, input и т.д.)
+// сюда не входят: они не разрешены и удаляются как неизвестные теги без изменения глубины удаления.
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "noscript": true,
+	"template": true, "svg": true, "math": true, "form": true, "textarea": true, "select": true, "button": true,
+}
+
+// Элементы, которые неявно закрываются следующим таким же элементом (<li>один<li>два)
+var implicitlyClosed = map[string]bool{"p": true, "li": true, "dt": true, "dd": true, "tr": true, "td": true, "th": true}
+
+// Пустые элементы без закрывающего тега
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true}
+
+// Атрибуты со ссылками и допустимые в них схемы
+var (
+	urlAttrs    = map[string]bool{"href": true, "src": true}
+	safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true, "": true}
+	//классы подсветки синтаксиса в pre/code
+	classRe  = regexp.MustCompile(`^(language|lang)-[\w+#-]+$`)
+	numberRe = regexp.MustCompile(`^\d{1,4}$`)
+)
+
+// Функция очистки HTML по списку разрешенных тегов и атрибутов. Сохраняет разметку текста, ссылки, списки,
+// таблицы и блоки кода, удаляет скрипты, стили, обработчики событий и ссылки с небезопасными схемами.
+// Незакрытые теги закрываются в конце фрагмента.
+func HTML(s string) string {
+	var b strings.Builder
+	var open []string
+	dropDepth := 0
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			//io.EOF или ошибка разбора - в обоих случаях фрагмент закончился
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.Data] {
+				if tt == html.StartTagToken {
+					dropDepth++
+				}
+				continue
+			}
+			if dropDepth > 0 {
+				continue
+			}
+			attrs, ok := allowedTags[tok.Data]
+			if !ok {
+				continue
+			}
+			if implicitlyClosed[tok.Data] && len(open) > 0 && open[len(open)-1] == tok.Data {
+				b.WriteString("</" + tok.Data + ">")
+				open = open[:len(open)-1]
+			}
+			writeStartTag(&b, tok, attrs)
+			if !voidTags[tok.Data] && tt == html.StartTagToken {
+				open = append(open, tok.Data)
+			}
+		case html.EndTagToken:
+			if droppedTags[tok.Data] {
+				if dropDepth > 0 {
+					dropDepth--
+				}
+				continue
+			}
+			if dropDepth > 0 {
+				continue
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != tok.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		case html.TextToken:
+			if dropDepth == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Функция записи открывающего тега с разрешенными атрибутами
+func writeStartTag(b *strings.Builder, tok html.Token, allowed []string) {
+	b.WriteString("<" + tok.Data)
+	for _, attr := range tok.Attr {
+		if attr.Namespace != "" || !contains(allowed, attr.Key) {
+			continue
+		}
+		val := strings.TrimSpace(attr.Val)
+		switch {
+		case urlAttrs[attr.Key]:
+			if !safeURL(val) {
+				continue
+			}
+		case attr.Key == "class":
+			if !classRe.MatchString(val) {
+				continue
+			}
+		case attr.Key == "width" || attr.Key == "height" || attr.Key == "start" || attr.Key == "colspan" || attr.Key == "rowspan":
+			if !numberRe.MatchString(val) {
+				continue
+			}
+		}
+		b.WriteString(" " + attr.Key + `="` + html.EscapeString(val) + `"`)
+	}
+	if tok.Data == "a" {
+		b.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	if voidTags[tok.Data] {
+		b.WriteString(" />")
+		return
+	}
+	b.WriteString(">")
+}
+
+// Функция проверки схемы ссылки
+func safeURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return safeSchemes[strings.ToLower(u.Scheme)]
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
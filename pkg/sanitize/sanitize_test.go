@@ -0,0 +1,69 @@
+package sanitize
+
+import "testing"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "Code block with language class",
+			input: `<pre class="language-go"><code class="language-go">if a &lt; b {}</code></pre>`,
+			want:  `<pre class="language-go"><code class="language-go">if a &lt; b {}</code></pre>`,
+		},
+		{
+			name:  "Link gets rel, unsafe attributes dropped",
+			input: `<a href="https://go.dev/" onclick="steal()" style="color:red">Go</a>`,
+			want:  `<a href="https://go.dev/" rel="nofollow noopener noreferrer">Go</a>`,
+		},
+		{
+			name:  "javascript URL dropped",
+			input: `<a href="javascript:alert(1)">x</a><img src="data:image/png;base64,AAAA" alt="pic">`,
+			want:  `<a rel="nofollow noopener noreferrer">x</a><img alt="pic" />`,
+		},
+		{
+			name:  "Script and style removed with content",
+			input: `<p>Hello<script>alert("x")</script><style>p{}</style> world</p>`,
+			want:  `<p>Hello world</p>`,
+		},
+		{
+			name:  "Void embed dropped without losing following content",
+			input: `<p>before<embed src="x.swf">after</p><p>more text</p>`,
+			want:  `<p>beforeafter</p><p>more text</p>`,
+		},
+		{
+			name:  "Self-closing embed keeps following content",
+			input: `<p>a<embed src="x.swf"/>b</p><p>c</p>`,
+			want:  `<p>ab</p><p>c</p>`,
+		},
+		{
+			name:  "Unknown tags unwrapped",
+			input: `<section><font color="red">text</font></section>`,
+			want:  `text`,
+		},
+		{
+			name:  "Lists and unclosed tags",
+			input: `<ul><li>one<li>two</ul><p><b>bold`,
+			want:  `<ul><li>one</li><li>two</li></ul><p><b>bold</b></p>`,
+		},
+		{
+			name:  "Text is escaped",
+			input: `a < b & "c"`,
+			want:  `a &lt; b &amp; &#34;c&#34;`,
+		},
+		{
+			name:  "Empty string",
+			input: "",
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.input); got != tt.want {
+				t.Errorf("HTML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Значения по умолчанию для задержки после ошибки опроса источника и минимального интервала опроса
+const (
+	DefaultMinBackoff  = 30 * time.Second
+	DefaultMaxBackoff  = 30 * time.Minute
+	DefaultMinInterval = time.Minute
+)
+
+// Задача опроса одного источника: ключ источника (уникален среди задач, используется в сообщениях об ошибках),
+// интервал опроса и функция загрузки статей. Version меняется при изменении настроек источника.
+type Job struct {
+	Source   string
+	Version  int64
+	Interval time.Duration
+	Fetch    func(ctx context.Context) ([]models.NewsFullDetailed, error)
+}
+
+// Запущенная задача и функция ее остановки
+type running struct {
+	job    Job
+	cancel context.CancelFunc
+}
+
+// Планировщик опроса источников. Каждый источник опрашивается в своей горутине со своим интервалом,
+// после ошибок выдерживается экспоненциальная задержка со случайным разбросом, а число одновременных
+// загрузок ограничено семафором.
+type Scheduler struct {
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+	MinInterval time.Duration //меньшие интервалы (в том числе нулевой) увеличиваются до него
+
+	news chan<- []models.NewsFullDetailed
+	errs chan<- error
+	sem  chan struct{}
+	wg   sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[string]running
+}
+
+// Конструктор планировщика. maxConcurrent - максимальное число одновременных загрузок (<1 - без ограничения),
+// news - канал для записи статей, errs - канал для записи ошибок опроса.
+func New(maxConcurrent int, news chan<- []models.NewsFullDetailed, errs chan<- error) *Scheduler {
+	s := Scheduler{
+		MinBackoff:  DefaultMinBackoff,
+		MaxBackoff:  DefaultMaxBackoff,
+		MinInterval: DefaultMinInterval,
+		news:        news,
+		errs:        errs,
+		jobs:        make(map[string]running),
+	}
+	if maxConcurrent > 0 {
+		s.sem = make(chan struct{}, maxConcurrent)
+	}
+	return &s
+}
+
+// Метод запускает опрос источников и блокируется до отмены контекста и завершения всех горутин.
+func (s *Scheduler) Run(ctx context.Context, jobs []Job) {
+	s.Sync(ctx, jobs)
+	<-ctx.Done()
+	s.Wait()
+}
+
+// Метод приводит набор опрашиваемых источников к переданному списку: запускает новые задачи, останавливает
+// задачи, которых нет в списке, и перезапускает задачи с изменившейся версией. Опрос прекращается при отмене контекста.
+func (s *Scheduler) Sync(ctx context.Context, jobs []Job) {
+	keep := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		keep[job.Source] = true
+		s.Add(ctx, job)
+	}
+	s.mu.Lock()
+	var stale []string
+	for source := range s.jobs {
+		if !keep[source] {
+			stale = append(stale, source)
+		}
+	}
+	s.mu.Unlock()
+	for _, source := range stale {
+		s.Remove(source)
+	}
+}
+
+// Метод запускает опрос одного источника. Если источник уже опрашивается с той же версией настроек, ничего не делает,
+// если с другой - перезапускает опрос. Интервал меньше MinInterval увеличивается до него, чтобы источник
+// не опрашивался в цикле без задержки. Опрос прекращается при отмене контекста или вызове Remove.
+func (s *Scheduler) Add(ctx context.Context, job Job) {
+	if job.Interval < s.MinInterval {
+		job.Interval = s.MinInterval
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.jobs[job.Source]; ok {
+		if r.job.Version == job.Version && r.job.Interval == job.Interval {
+			return
+		}
+		r.cancel()
+	}
+	jobCtx, cancel := context.WithCancel(ctx)
+	s.jobs[job.Source] = running{job: job, cancel: cancel}
+	s.wg.Add(1)
+	go s.loop(jobCtx, job)
+}
+
+// Метод останавливает опрос источника.
+func (s *Scheduler) Remove(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.jobs[source]; ok {
+		r.cancel()
+		delete(s.jobs, source)
+	}
+}
+
+// Метод ожидает завершения опроса всех источников.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// Цикл опроса источника
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	failures := 0
+	for {
+		news, err := s.fetch(ctx, job)
+		if ctx.Err() != nil {
+			return
+		}
+		var delay time.Duration
+		if err != nil {
+			failures++
+			delay = Backoff(failures, s.MinBackoff, s.MaxBackoff)
+			select {
+			case s.errs <- fmt.Errorf("source %s: %w (retry in %v)", job.Source, err, delay.Round(time.Second)):
+			case <-ctx.Done():
+				return
+			}
+		} else {
+			failures = 0
+			delay = Jitter(job.Interval)
+			if len(news) > 0 {
+				select {
+				case s.news <- news:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Метод загрузки статей источника с учетом ограничения на число одновременных загрузок
+func (s *Scheduler) fetch(ctx context.Context, job Job) ([]models.NewsFullDetailed, error) {
+	if s.sem != nil {
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return job.Fetch(ctx)
+}
+
+// Функция расчета задержки после failures подряд неудачных попыток: min*2^(failures-1), но не больше max,
+// со случайным разбросом в пределах [d/2, d], чтобы сломанные источники не опрашивались синхронно.
+func Backoff(failures int, min, max time.Duration) time.Duration {
+	d := min
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Функция добавляет к интервалу опроса случайный разброс ±10%
+func Jitter(interval time.Duration) time.Duration {
+	spread := int64(interval / 10)
+	if spread <= 0 {
+		return interval
+	}
+	return interval - time.Duration(spread) + time.Duration(rand.Int63n(2*spread+1))
+}
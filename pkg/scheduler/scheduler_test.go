@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestBackoff(t *testing.T) {
+	min, max := time.Second, 10*time.Second
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "First failure", failures: 1, want: time.Second},
+		{name: "Third failure", failures: 3, want: 4 * time.Second},
+		{name: "Capped by max", failures: 20, want: max},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := Backoff(tt.failures, min, max)
+				if got < tt.want/2 || got > tt.want {
+					t.Fatalf("Backoff(%d) = %v, want in [%v, %v]", tt.failures, got, tt.want/2, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestJitter(t *testing.T) {
+	interval := 10 * time.Minute
+	for i := 0; i < 100; i++ {
+		got := Jitter(interval)
+		if got < 9*time.Minute || got > 11*time.Minute {
+			t.Fatalf("Jitter(%v) = %v, want within 10%%", interval, got)
+		}
+	}
+}
+
+// Тест проверяет, что сломанный источник не опрашивается в цикле без задержки
+// и что планировщик завершает работу при отмене контекста
+func TestScheduler_BackoffAndCancel(t *testing.T) {
+	news := make(chan []models.NewsFullDetailed)
+	errs := make(chan error, 100)
+	s := New(1, news, errs)
+	s.MinBackoff = 50 * time.Millisecond
+	s.MaxBackoff = 50 * time.Millisecond
+
+	var calls int32
+	job := Job{
+		Source:   "broken",
+		Interval: time.Minute,
+		Fetch: func(ctx context.Context) ([]models.NewsFullDetailed, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, errors.New("broken feed")
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, []Job{job})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop after context cancellation")
+	}
+	if got := atomic.LoadInt32(&calls); got < 2 || got > 6 {
+		t.Errorf("broken source fetched %d times, want between 2 and 6", got)
+	}
+}
+
+// Тест проверяет, что источник с нулевым интервалом опрашивается не чаще MinInterval
+func TestScheduler_MinInterval(t *testing.T) {
+	s := New(0, make(chan []models.NewsFullDetailed), make(chan error, 100))
+	if s.MinInterval != DefaultMinInterval {
+		t.Fatalf("MinInterval = %v, want %v", s.MinInterval, DefaultMinInterval)
+	}
+	s.MinInterval = 50 * time.Millisecond
+
+	var calls int32
+	job := Job{
+		Source: "no interval",
+		Fetch: func(ctx context.Context) ([]models.NewsFullDetailed, error) {
+			atomic.AddInt32(&calls, 1)
+			return nil, nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+	s.Run(ctx, []Job{job})
+	if got := atomic.LoadInt32(&calls); got < 2 || got > 4 {
+		t.Errorf("source without interval fetched %d times, want between 2 and 4", got)
+	}
+}
+
+// Тест проверяет ограничение на число одновременных загрузок
+func TestScheduler_MaxConcurrent(t *testing.T) {
+	news := make(chan []models.NewsFullDetailed, 100)
+	errs := make(chan error, 100)
+	s := New(2, news, errs)
+
+	var running, peak int32
+	fetch := func(ctx context.Context) ([]models.NewsFullDetailed, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return []models.NewsFullDetailed{{Title: "Test Title"}}, nil
+	}
+
+	var jobs []Job
+	for _, source := range []string{"a", "b", "c", "d", "e"} {
+		jobs = append(jobs, Job{Source: source, Interval: time.Hour, Fetch: fetch})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	s.Run(ctx, jobs)
+
+	if got := atomic.LoadInt32(&peak); got > 2 {
+		t.Errorf("peak concurrent fetches = %d, want <= 2", got)
+	}
+	if got := len(news); got != len(jobs) {
+		t.Errorf("got %d batches of news, want %d", got, len(jobs))
+	}
+}
+
+// Тест проверяет, что Sync запускает новые, перезапускает измененные и останавливает удаленные источники
+func TestScheduler_Sync(t *testing.T) {
+	news := make(chan []models.NewsFullDetailed, 100)
+	errs := make(chan error, 100)
+	s := New(0, news, errs)
+
+	var calls sync.Map
+	job := func(source string, version int64) Job {
+		return Job{
+			Source:   source,
+			Version:  version,
+			Interval: time.Hour,
+			Fetch: func(ctx context.Context) ([]models.NewsFullDetailed, error) {
+				n, _ := calls.LoadOrStore(source, new(int32))
+				atomic.AddInt32(n.(*int32), 1)
+				return nil, nil
+			},
+		}
+	}
+	count := func(source string) int32 {
+		n, ok := calls.Load(source)
+		if !ok {
+			return 0
+		}
+		return atomic.LoadInt32(n.(*int32))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Sync(ctx, []Job{job("a", 1), job("b", 1)})
+	time.Sleep(20 * time.Millisecond)
+	//"a" не изменился, "b" изменился, "c" добавлен
+	s.Sync(ctx, []Job{job("a", 1), job("b", 2), job("c", 1)})
+	time.Sleep(20 * time.Millisecond)
+	s.Sync(ctx, []Job{job("c", 1)})
+
+	if got := count("a"); got != 1 {
+		t.Errorf("unchanged source fetched %d times, want 1", got)
+	}
+	if got := count("b"); got != 2 {
+		t.Errorf("changed source fetched %d times, want 2", got)
+	}
+	if got := count("c"); got != 1 {
+		t.Errorf("new source fetched %d times, want 1", got)
+	}
+	s.mu.Lock()
+	active := len(s.jobs)
+	s.mu.Unlock()
+	if active != 1 {
+		t.Errorf("scheduler has %d active jobs, want 1", active)
+	}
+
+	cancel()
+	s.Wait()
+}
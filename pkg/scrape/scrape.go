@@ -0,0 +1,155 @@
+package scrape
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/sanitize"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html/charset"
+)
+
+// Функция проверки селекторов источника
+func Validate(opts *models.ScrapeOptions) error {
+	if opts == nil {
+		return errors.New("scrape selectors are required")
+	}
+	if opts.Item == "" || opts.Title == "" || opts.Link == "" {
+		return errors.New("scrape item, title and link selectors are required")
+	}
+	for name, sel := range map[string]string{
+		"item": opts.Item, "title": opts.Title, "link": opts.Link, "date": opts.Date,
+		"summary": opts.Summary, "author": opts.Author, "image": opts.Image,
+	} {
+		if sel == "" {
+			continue
+		}
+		if _, err := cascadia.Compile(sel); err != nil {
+			return fmt.Errorf("invalid scrape %s selector %q: %w", name, sel, err)
+		}
+	}
+	return nil
+}
+
+// Функция загрузки страницы со списком статей и ее разбора функцией Parse. Если страница не изменилась
+// (ответ 304), возвращается пустой слайс без ошибки.
+func Fetch(ctx context.Context, f fetcher.Fetcher, source string, opts *models.ScrapeOptions) ([]models.NewsFullDetailed, error) {
+	if err := Validate(opts); err != nil {
+		return nil, err
+	}
+	resp, err := f.Fetch(ctx, source)
+	if err != nil || len(resp.Body) == 0 {
+		return nil, err
+	}
+	base, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	r, err := charset.NewReader(bytes.NewReader(resp.Body), resp.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	news, err := Parse(r, base, *opts)
+	if err != nil {
+		return nil, err
+	}
+	if c, ok := f.(fetcher.Conditional); ok {
+		c.Remember(source, resp)
+	}
+	return news, nil
+}
+
+// Функция разбора страницы со списком статей по CSS-селекторам. Относительные ссылки разрешаются
+// относительно base (или <base href> страницы). Блоки без заголовка или ссылки пропускаются.
+func Parse(r io.Reader, base *url.URL, opts models.ScrapeOptions) ([]models.NewsFullDetailed, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+	doc.Find("script, style, noscript").Remove()
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(href); err == nil {
+			base = u
+		}
+	}
+	fetched := time.Now()
+
+	var news []models.NewsFullDetailed
+	doc.Find(opts.Item).Each(func(_ int, item *goquery.Selection) {
+		n := models.NewsFullDetailed{
+			Title: text(item.Find(opts.Title).First()),
+			Link:  resolve(base, attr(item, opts.Link, "href")),
+		}
+		if n.Title == "" || n.Link == "" {
+			return
+		}
+		n.GUID = n.Link
+		var t time.Time
+		var rule string
+		if opts.Date != "" {
+			date := item.Find(opts.Date).First()
+			value, ok := date.Attr("datetime")
+			if !ok {
+				value = text(date)
+			}
+			if d, ok := rss.ParseDate(value); ok {
+				t, rule = d, rss.DateFromLayout
+			}
+		}
+		n.Published, n.PublishedSource = rss.CheckDate(t, rule, fetched)
+		if opts.Summary != "" {
+			summary := item.Find(opts.Summary).First()
+			n.Content = text(summary)
+			if h, err := summary.Html(); err == nil {
+				n.ContentHTML = sanitize.HTML(h)
+			}
+		}
+		if opts.Author != "" {
+			n.Author = text(item.Find(opts.Author).First())
+		}
+		if opts.Image != "" {
+			n.ImageURL = resolve(base, attr(item, opts.Image, "src"))
+		}
+		news = append(news, n)
+	})
+	return news, nil
+}
+
+// Текст элемента без лишних пробелов
+func text(s *goquery.Selection) string {
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+// Значение атрибута первого подходящего элемента. Если у элемента нет атрибута, ищется первый потомок с ним
+// (например селектор заголовка h2, внутри которого ссылка).
+func attr(item *goquery.Selection, selector, name string) string {
+	el := item.Find(selector).First()
+	if v, ok := el.Attr(name); ok {
+		return strings.TrimSpace(v)
+	}
+	v, _ := el.Find("[" + name + "]").First().Attr(name)
+	return strings.TrimSpace(v)
+}
+
+// Абсолютная ссылка http(s) относительно адреса страницы, иначе пустая строка
+func resolve(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
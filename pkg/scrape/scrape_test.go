@@ -0,0 +1,109 @@
+package scrape
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func TestParse(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "blog.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	base, _ := url.Parse("https://example.com/blog")
+	opts := models.ScrapeOptions{
+		Item:    "article.post-card",
+		Title:   ".post-title",
+		Link:    ".post-title",
+		Date:    "time",
+		Summary: ".excerpt",
+		Author:  ".author",
+		Image:   ".thumb img",
+	}
+
+	got, err := Parse(f, base, opts)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Parse() returned %d items, want 2: %+v", len(got), got)
+	}
+	want := models.NewsFullDetailed{
+		Title:           "Zero-downtime schema migrations",
+		Link:            "https://example.com/blog/2024/10/zero-downtime-migrations",
+		GUID:            "https://example.com/blog/2024/10/zero-downtime-migrations",
+		Author:          "Jane Doe",
+		Published:       time.Date(2024, 10, 28, 9, 0, 0, 0, time.UTC).Unix(),
+		PublishedSource: rss.DateFromLayout,
+		Content:         "How we migrate large PostgreSQL tables without locking.",
+		ContentHTML:     "How we migrate <strong>large</strong> PostgreSQL tables without locking.",
+		ImageURL:        "https://example.com/img/migrations.png",
+	}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Parse() first item = %+v, want %+v", got[0], want)
+	}
+	if got[1].Link != "https://example.com/blog/2024/10/go-profiling" ||
+		got[1].Published != time.Date(2024, 10, 21, 0, 0, 0, 0, time.UTC).Unix() || got[1].ImageURL != "" {
+		t.Errorf("Parse() second item = %+v", got[1])
+	}
+}
+
+// Тест загружает сохраненную страницу в windows-1251 и проверяет перекодирование и разрешение ссылок
+func TestFetch(t *testing.T) {
+	opts := &models.ScrapeOptions{Item: "ul.news li", Title: "a.news-link", Link: "a.news-link", Date: ".date", Summary: ".lead"}
+
+	page, err := os.ReadFile(filepath.Join("testdata", "news-cp1251.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := fetcher.Memory{"https://company.example/news/": page}
+
+	got, err := Fetch(context.Background(), f, "https://company.example/news/", opts)
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	var titles []string
+	for _, n := range got {
+		titles = append(titles, n.Title)
+	}
+	wantTitles := []string{"Вышла версия 2.0", "Открыт новый офис в Казани"}
+	if !reflect.DeepEqual(titles, wantTitles) {
+		t.Fatalf("Fetch() titles = %q, want %q", titles, wantTitles)
+	}
+	if got[0].Content != "Основные изменения и план миграции." || got[0].Link != "https://company.example/news/2024/10/29/release/" {
+		t.Errorf("Fetch() first item = %+v", got[0])
+	}
+	if got[1].Published != time.Date(2024, 10, 25, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Fetch() published = %v", time.Unix(got[1].Published, 0).UTC())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *models.ScrapeOptions
+		wantErr bool
+	}{
+		{name: "Valid", opts: &models.ScrapeOptions{Item: "article", Title: "h2", Link: "h2 a", Date: "time"}},
+		{name: "Missing options", opts: nil, wantErr: true},
+		{name: "Missing link", opts: &models.ScrapeOptions{Item: "article", Title: "h2"}, wantErr: true},
+		{name: "Invalid selector", opts: &models.ScrapeOptions{Item: "article[", Title: "h2", Link: "a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+// Максимальное число карт сайта, загружаемых из одного индекса
+const maxSitemaps = 10
+
+// Максимальная глубина вложенности индексов карт сайта
+const maxDepth = 2
+
+// Карта сайта (urlset) с расширением Google News
+type urlset struct {
+	URLs []url `xml:"url"`
+}
+
+type url struct {
+	Loc     string  `xml:"loc"`
+	LastMod string  `xml:"lastmod"`
+	News    *news   `xml:"http://www.google.com/schemas/sitemap-news/0.9 news"`
+	Images  []image `xml:"http://www.google.com/schemas/sitemap-image/1.1 image"`
+}
+
+type news struct {
+	Publication struct {
+		Name     string `xml:"name"`
+		Language string `xml:"language"`
+	} `xml:"publication"`
+	PublicationDate string `xml:"publication_date"`
+	Title           string `xml:"title"`
+	Keywords        string `xml:"keywords"`
+}
+
+type image struct {
+	Loc string `xml:"loc"`
+}
+
+// Индекс карт сайта (sitemapindex)
+type index struct {
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+// Функция загрузки карты сайта или индекса карт сайта и преобразования записей <news:news> в статьи.
+// Записи без <news:news> пропускаются: у них нет заголовка. Из индекса загружаются не больше maxSitemaps
+// карт с самой поздней датой изменения. Сжатые gzip карты распаковываются.
+func Fetch(ctx context.Context, f fetcher.Fetcher, source string) ([]models.NewsFullDetailed, error) {
+	return fetch(ctx, f, source, 0)
+}
+
+func fetch(ctx context.Context, f fetcher.Fetcher, source string, depth int) ([]models.NewsFullDetailed, error) {
+	resp, err := f.Fetch(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	fetched := time.Now()
+	body, err := decompress(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	root, err := rootElement(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	var result []models.NewsFullDetailed
+	switch root {
+	case "urlset":
+		var set urlset
+		if err = xml.Unmarshal(body, &set); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		result = toNews(set, fetched)
+		//валидаторы запоминаются только для карт: индекс часто не меняется, когда меняются карты в нем
+		if c, ok := f.(fetcher.Conditional); ok {
+			c.Remember(source, resp)
+		}
+	case "sitemapindex":
+		if depth >= maxDepth {
+			return nil, fmt.Errorf("%s: sitemap index nesting is too deep", source)
+		}
+		var idx index
+		if err = xml.Unmarshal(body, &idx); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		//сначала самые свежие карты; карты без даты изменения - в порядке индекса после датированных
+		sort.SliceStable(idx.Sitemaps, func(i, j int) bool {
+			return idx.Sitemaps[i].LastMod > idx.Sitemaps[j].LastMod
+		})
+		for i, s := range idx.Sitemaps {
+			if i == maxSitemaps {
+				break
+			}
+			loc, err := childURL(source, s.Loc)
+			if err != nil {
+				log.Printf("Sitemap error - %s: %v", source, err)
+				continue
+			}
+			items, err := fetch(ctx, f, loc, depth+1)
+			if err != nil {
+				//ошибка одной карты не отменяет остальные
+				log.Printf("Sitemap error - %v", err)
+				continue
+			}
+			result = append(result, items...)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unexpected root element <%s>", source, root)
+	}
+	return result, nil
+}
+
+// Функция проверки адреса карты из индекса. Адрес разрешается относительно адреса индекса; допускаются только
+// http(s):// на том же хосте, что и индекс, чтобы индекс не мог направить загрузку на локальные файлы,
+// внутренние адреса или сторонние сайты.
+func childURL(parent, loc string) (string, error) {
+	base, err := neturl.Parse(parent)
+	if err != nil {
+		return "", err
+	}
+	u, err := base.Parse(strings.TrimSpace(loc))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("sitemap %q: unsupported scheme", loc)
+	}
+	if !strings.EqualFold(u.Hostname(), base.Hostname()) {
+		return "", fmt.Errorf("sitemap %q: host differs from index host %s", loc, base.Hostname())
+	}
+	return u.String(), nil
+}
+
+// Преобразование записей карты сайта в статьи
+func toNews(set urlset, fetched time.Time) []models.NewsFullDetailed {
+	var result []models.NewsFullDetailed
+	for _, u := range set.URLs {
+		if u.News == nil || strings.TrimSpace(u.News.Title) == "" {
+			continue
+		}
+		n := models.NewsFullDetailed{
+			Title:  strings.TrimSpace(u.News.Title),
+			Link:   strings.TrimSpace(u.Loc),
+			Author: strings.TrimSpace(u.News.Publication.Name),
+		}
+		var t time.Time
+		var rule string
+		if d, ok := rss.ParseDate(strings.TrimSpace(u.News.PublicationDate)); ok {
+			t, rule = d, rss.DateFromPublished
+		} else if d, ok := rss.ParseDate(strings.TrimSpace(u.LastMod)); ok {
+			t, rule = d, rss.DateFromUpdated
+		}
+		n.Published, n.PublishedSource = rss.CheckDate(t, rule, fetched)
+		for _, k := range strings.Split(u.News.Keywords, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				n.Categories = append(n.Categories, k)
+			}
+		}
+		if len(u.Images) > 0 {
+			n.ImageURL = strings.TrimSpace(u.Images[0].Loc)
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// Распаковка карты сайта, сжатой gzip (определяется по сигнатуре, а не по расширению файла)
+func decompress(body []byte) ([]byte, error) {
+	if len(body) < 2 || body[0] != 0x1f || body[1] != 0x8b {
+		return body, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(io.LimitReader(r, fetcher.MaxSize))
+}
+
+// Имя корневого элемента документа
+func rootElement(body []byte) (string, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", err
+		}
+		if el, ok := tok.(xml.StartElement); ok {
+			return el.Name.Local, nil
+		}
+	}
+}
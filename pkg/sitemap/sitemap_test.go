@@ -0,0 +1,130 @@
+package sitemap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"Skillfactory/36-GoNews/pkg/fetcher"
+	"Skillfactory/36-GoNews/pkg/rss"
+	"Skillfactory/36-GoNews/pkg/storage/models"
+)
+
+func readFile(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestFetch(t *testing.T) {
+	want := []models.NewsFullDetailed{
+		{
+			Title:           "ЦБ сохранил ключевую ставку",
+			Link:            "https://www.kommersant.ru/doc/7265001",
+			Author:          "Коммерсантъ",
+			Published:       time.Date(2024, 10, 29, 11, 20, 0, 0, time.UTC).Unix(),
+			PublishedSource: rss.DateFromPublished,
+			Categories:      []string{"Экономика", "ЦБ", "ставка"},
+			ImageURL:        "https://im.kommersant.ru/Issues.photo/CORP/2024/10/29/KMO_111307_55555_1_t222_142000.jpg",
+		},
+		{
+			Title:           "Новый сезон отопления начался досрочно",
+			Link:            "https://www.kommersant.ru/doc/7264900",
+			Author:          "Коммерсантъ",
+			Published:       time.Date(2024, 10, 29, 9, 5, 0, 0, time.UTC).Unix(),
+			PublishedSource: rss.DateFromUpdated,
+		},
+	}
+	plain := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+	<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"><url><loc>https://www.kommersant.ru/</loc></url></urlset>`)
+	f := fetcher.Mux{
+		"file": fetcher.File{},
+		"https": fetcher.Memory{
+			"https://www.kommersant.ru/sitemap.xml":             readFile(t, "sitemap-index.xml"),
+			"https://www.kommersant.ru/sitemaps/news.xml.gz":    readFile(t, "news-sitemap.xml.gz"),
+			"https://www.kommersant.ru/sitemaps/old.xml":        plain,
+			"https://www.kommersant.ru/sitemaps/not-a-map.html": []byte(`<html></html>`),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		want    []models.NewsFullDetailed
+		wantErr bool
+	}{
+		{name: "News sitemap", source: "file://testdata/news-sitemap.xml", want: want},
+		{name: "Sitemap index with gzip and broken maps", source: "https://www.kommersant.ru/sitemap.xml", want: want},
+		{name: "Plain sitemap", source: "https://www.kommersant.ru/sitemaps/old.xml"},
+		{name: "Not a sitemap", source: "https://www.kommersant.ru/sitemaps/not-a-map.html", wantErr: true},
+		{name: "Missing", source: "https://www.kommersant.ru/missing.xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fetch(context.Background(), f, tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Fetch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Тест проверяет, что из индекса загружаются только карты http(s):// с хоста индекса, а относительные адреса
+// разрешаются относительно индекса
+func TestFetch_IndexChildren(t *testing.T) {
+	idx := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+	<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>file:///etc/passwd</loc></sitemap>
+	<sitemap><loc>file://testdata/news-sitemap.xml</loc></sitemap>
+	<sitemap><loc>https://evil.example/news.xml</loc></sitemap>
+	<sitemap><loc>http://169.254.169.254/latest/meta-data/</loc></sitemap>
+	<sitemap><loc>ftp://www.kommersant.ru/news.xml</loc></sitemap>
+	<sitemap><loc>/sitemaps/news.xml</loc></sitemap>
+	</sitemapindex>`)
+	news := readFile(t, "news-sitemap.xml")
+	f := fetcher.Mux{
+		"file": fetcher.File{},
+		"http": fetcher.Memory{"http://169.254.169.254/latest/meta-data/": news},
+		"https": fetcher.Memory{
+			"https://www.kommersant.ru/sitemap.xml":       idx,
+			"https://www.kommersant.ru/sitemaps/news.xml": news,
+			"https://evil.example/news.xml":               news,
+		},
+	}
+	got, err := Fetch(context.Background(), f, "https://www.kommersant.ru/sitemap.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Fetch() returned %d items, want 2 from the relative sitemap only", len(got))
+	}
+
+	tests := []struct {
+		loc     string
+		want    string
+		wantErr bool
+	}{
+		{loc: " /sitemaps/news.xml ", want: "https://www.kommersant.ru/sitemaps/news.xml"},
+		{loc: "https://WWW.kommersant.ru/a.xml", want: "https://WWW.kommersant.ru/a.xml"},
+		{loc: "http://www.kommersant.ru/a.xml", want: "http://www.kommersant.ru/a.xml"},
+		{loc: "file:///etc/passwd", wantErr: true},
+		{loc: "https://evil.example/a.xml", wantErr: true},
+		{loc: "//evil.example/a.xml", wantErr: true},
+		{loc: "gopher://www.kommersant.ru/a.xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := childURL("https://www.kommersant.ru/sitemap.xml", tt.loc)
+		if (err != nil) != tt.wantErr || got != tt.want && !tt.wantErr {
+			t.Errorf("childURL(%q) = %q, %v, want %q, wantErr %v", tt.loc, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
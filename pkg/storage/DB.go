@@ -9,7 +9,7 @@ import (
 type DbInterface interface {
 	GetDetailedNews(int) (models.NewsFullDetailed, error)
 	GetNewsList(int) ([]models.NewsFullDetailed, error)
-	AddNews([]models.NewsFullDetailed) error
+	AddNews([]models.NewsFullDetailed) (models.IngestStats, error)
 	//TODO:add method GetNewsList
 }
 
@@ -26,7 +26,7 @@ func GetDetailedNews(id int, db DbInterface) (models.NewsFullDetailed, error) {
 
 // Метод добавления статьи
 func Add(db DbInterface, news []models.NewsFullDetailed) error {
-	err := db.AddNews(news)
+	_, err := db.AddNews(news)
 	if err != nil {
 		log.Fatalf("Error when ADD article to database: %v\n", err)
 		return err
@@ -1,12 +1,199 @@
 package models
 
 type NewsFullDetailed struct {
-	ID        int    `db:"id"`
-	Title     string `db:"title"`
-	Content   string `db:"description"`
-	Preview   string `db:"preview"`
-	Published int64  `db:"published"`
-	Link      string `db:"link"`
+	ID              int         `db:"id"`
+	Title           string      `db:"title"`
+	Content         string      `db:"description"`  //текст без разметки для поиска и превью
+	ContentHTML     string      `db:"content_html"` //очищенный HTML с безопасной разметкой (код, ссылки, списки)
+	FullContent     string      `db:"full_content"` //полный текст со страницы статьи (для источников с режимом full_text)
+	Preview         string      `db:"preview"`
+	Published       int64       `db:"published"`
+	PublishedSource string      `db:"published_source"` //правило, по которому определена дата публикации
+	Link            string      `db:"link"`             //ссылка на статью, по ней загружается страница и она показывается читателю
+	LinkKey         string      `db:"link_key"`         //канонический вид ссылки, по нему проверяется уникальность статьи
+	Author          string      `db:"author"`
+	Categories      []string    `db:"categories"`
+	GUID            string      `db:"guid"` //уникальный идентификатор статьи в ленте, не меняется при смене ссылки
+	Enclosures      []Enclosure `db:"enclosures"`
+	ImageURL        string      `db:"image_url"`    //миниатюра статьи
+	SourceID        int         `db:"source_id"`    //источник, из которого получена статья
+	Fingerprint     int64       `db:"fingerprint"`  //simhash заголовка и текста для поиска почти одинаковых статей
+	CanonicalID     int         `db:"canonical_id"` //ID исходной статьи, если статья - почти дубликат
+	Updated         int64       `db:"updated_at"`   //время сохранения текущей версии статьи
+	Rank            float32     `db:"rank"`         //релевантность статьи поисковому запросу
+	Snippet         string      `db:"snippet"`      //фрагменты текста с найденными словами, выделенными тегом <mark>
+}
+
+// Версия статьи. Revision - порядковый номер версии начиная с 1, последняя версия - текущая статья.
+type Revision struct {
+	Revision    int    `db:"revision" json:"revision"`
+	Title       string `db:"title" json:"title"`
+	Content     string `db:"content" json:"content"`
+	ContentHTML string `db:"content_html" json:"content_html"`
+	CreatedAt   int64  `db:"created_at" json:"created_at"` //время получения версии
+	Current     bool   `json:"current"`
+}
+
+// Вложение статьи (медиафайл из тега enclosure)
+type Enclosure struct {
+	URL    string
+	Type   string
+	Length int64
+}
+
+// Источник новостей
+type Source struct {
+	ID        int           `db:"id" json:"id"`
+	URL       string        `db:"url" json:"url"`
+	Title     string        `db:"title" json:"title"`
+	Group     string        `db:"group_name" json:"group"`  //папка источника, вложенные папки через "/" ("\/" - символ / в названии папки)
+	Interval  int           `db:"interval" json:"interval"` //интервал опроса в минутах, 0 - общий интервал из настроек
+	Enabled   bool          `db:"enabled" json:"enabled"`
+	Options   SourceOptions `db:"options" json:"options"`
+	UpdatedAt int64         `db:"updated_at" json:"updated_at"`
+}
+
+// Настройки обработки статей источника
+type SourceOptions struct {
+	Type     string         `json:"type,omitempty"`   //тип источника: rss (по умолчанию), sitemap, scrape или json
+	FullText bool           `json:"full_text"`        //загружать полный текст статей со страниц по ссылкам
+	Rules    []Rule         `json:"rules,omitempty"`  //правила отбора и изменения статей перед сохранением
+	Scrape   *ScrapeOptions `json:"scrape,omitempty"` //селекторы для источников типа scrape
+	JSON     *JSONOptions   `json:"json,omitempty"`   //сопоставление полей для источников типа json
+	HTTP     *HTTPOptions   `json:"http,omitempty"`   //настройки HTTP-клиента источника
+}
+
+// Настройки HTTP-клиента источника. Секреты в конфигурации не хранятся: пароль и токен читаются
+// из переменных окружения с указанными именами, в значениях заголовков и адресе прокси подставляются
+// переменные окружения вида $NAME или ${NAME}. Допускаются только переменные с префиксом GONEWS_SECRET_.
+type HTTPOptions struct {
+	Auth      *AuthOptions      `json:"auth,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Proxy     string            `json:"proxy,omitempty"`   //адрес прокси, например http://proxy.local:3128
+	Timeout   int               `json:"timeout,omitempty"` //таймаут запроса в секундах, 0 - по умолчанию
+	TLS       *TLSOptions       `json:"tls,omitempty"`
+}
+
+// Авторизация запросов к источнику: Type - basic или bearer
+type AuthOptions struct {
+	Type        string `json:"type"`
+	Username    string `json:"username,omitempty"`
+	PasswordEnv string `json:"password_env,omitempty"` //переменная окружения с паролем для basic
+	TokenEnv    string `json:"token_env,omitempty"`    //переменная окружения с токеном для bearer
+}
+
+// Настройки TLS: собственный корневой сертификат, клиентский сертификат и отключение проверки сертификата
+type TLSOptions struct {
+	CAFile             string `json:"ca_file,omitempty"`
+	CertFile           string `json:"cert_file,omitempty"`
+	KeyFile            string `json:"key_file,omitempty"`
+	ServerName         string `json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// CSS-селекторы страницы со списком статей. Item - блок одной статьи, остальные селекторы применяются
+// внутри блока: ссылка берется из атрибута href, дата - из атрибута datetime или текста элемента,
+// картинка - из атрибута src. Обязательны Item, Title и Link.
+type ScrapeOptions struct {
+	Item    string `json:"item"`
+	Title   string `json:"title"`
+	Link    string `json:"link"`
+	Date    string `json:"date,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Image   string `json:"image,omitempty"`
+}
+
+// Сопоставление полей ответа JSON API полям статьи. Пути задаются через точку с индексами массивов,
+// например "data.items" или "media[0].url". Items - путь к массиву статей от корня документа, остальные
+// пути - от объекта статьи; пустой Items - корень документа является массивом. Обязательны Title и Link.
+// Дата берется из строки известного формата (или формата DateLayout)
+// либо из числа секунд или миллисекунд Unix.
+type JSONOptions struct {
+	Items      string `json:"items"`
+	Title      string `json:"title"`
+	Link       string `json:"link"`
+	Content    string `json:"content,omitempty"`
+	Published  string `json:"published,omitempty"`
+	DateLayout string `json:"date_layout,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Categories string `json:"categories,omitempty"`
+	Image      string `json:"image,omitempty"`
+	GUID       string `json:"guid,omitempty"`
+}
+
+// Типы источников
+const (
+	SourceRSS     = "rss"
+	SourceSitemap = "sitemap" //карта сайта с расширением Google News или индекс карт
+	SourceScrape  = "scrape"  //HTML-страница со списком статей, разбираемая CSS-селекторами
+	SourceJSON    = "json"    //JSON API со списком статей
+)
+
+// Правило обработки статей источника. Action - include (оставить только подходящие статьи), exclude (отбросить
+// подходящие статьи) или rewrite (заменить в поле совпадения на Replace). Field - title, content, category
+// или author. Совпадение задается ключевым словом Match (без учета регистра) или регулярным выражением Regex.
+type Rule struct {
+	Action  string `json:"action"`
+	Field   string `json:"field"`
+	Match   string `json:"match,omitempty"`
+	Regex   string `json:"regex,omitempty"`
+	Replace string `json:"replace,omitempty"`
+}
+
+// Состояние опроса источника
+type SourceHealth struct {
+	SourceID            int    `db:"source_id" json:"source_id"`
+	URL                 string `db:"url" json:"url"`
+	Title               string `db:"title" json:"title"`
+	Enabled             bool   `db:"enabled" json:"enabled"`
+	LastAttempt         int64  `db:"last_attempt" json:"last_attempt"`
+	LastSuccess         int64  `db:"last_success" json:"last_success"`
+	ConsecutiveFailures int    `db:"consecutive_failures" json:"consecutive_failures"`
+	LastError           string `db:"last_error" json:"last_error"`
+	LastErrorAt         int64  `db:"last_error_at" json:"last_error_at"`
+	HTTPStatus          int    `db:"http_status" json:"http_status"`
+	LatencyMs           int64  `db:"latency_ms" json:"latency_ms"`
+	ItemsLastFetch      int    `db:"items_last_fetch" json:"items_last_fetch"`
+	TotalFetches        int64  `db:"total_fetches" json:"total_fetches"`
+	TotalFailures       int64  `db:"total_failures" json:"total_failures"`
+}
+
+// Подписка WebSub на обновления источника
+type Subscription struct {
+	SourceID     int    `db:"source_id" json:"source_id"`
+	Hub          string `db:"hub" json:"hub"`
+	Topic        string `db:"topic" json:"topic"`
+	Secret       string `db:"secret" json:"-"`
+	State        string `db:"state" json:"state"`                 //pending, active или denied
+	LeaseExpires int64  `db:"lease_expires" json:"lease_expires"` //время окончания подписки, 0 - не подтверждена
+	UpdatedAt    int64  `db:"updated_at" json:"updated_at"`
+}
+
+// Состояния подписки WebSub
+const (
+	SubscriptionPending = "pending"
+	SubscriptionActive  = "active"
+	SubscriptionDenied  = "denied"
+)
+
+// Результат одной попытки опроса источника
+type FetchResult struct {
+	SourceID   int
+	Time       int64 //время начала попытки
+	Err        error
+	HTTPStatus int
+	LatencyMs  int64
+	Items      int
+}
+
+// Итог сохранения пакета статей: добавлено новых, обновлено измененных и пропущено (без изменений
+// или уже добавленных параллельно)
+type IngestStats struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
 }
 
 type NewsShortDetailed struct {
@@ -24,11 +211,23 @@ type Comment struct {
 	Сensor    bool   `db:"censor"` //true-прошел цензуру/false - нет
 }
 
-// Объкт пагинации
+// Объкт пагинации. При выводе по курсору количество результатов и страниц не считается, вместо номера
+// страницы возвращаются курсоры следующей и предыдущей страниц (пустые, если страницы нет).
 type Pagination struct {
 	TotalResulst int                `json:"total_results"`
 	TotalPages   int                `json:"total_pages"`
 	CurrentPage  int                `json:"current_page"`
 	NewsPerPage  int                `json:"news_per_page"`
+	NextCursor   string             `json:"next_cursor,omitempty"`
+	PrevCursor   string             `json:"prev_cursor,omitempty"`
 	Results      []NewsFullDetailed `json:"results"`
 }
+
+// Позиция в списке новостей, упорядоченном по убыванию (Rank, Published, ID). Rank учитывается только
+// в результатах поиска. Before - страница перед позицией (более новые статьи), иначе - после нее.
+type Cursor struct {
+	Rank      float32 `json:"r,omitempty"`
+	Published int64   `json:"p"`
+	ID        int     `json:"i"`
+	Before    bool    `json:"b,omitempty"`
+}
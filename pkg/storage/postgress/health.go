@@ -0,0 +1,81 @@
+package postgress
+
+import (
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"context"
+	"fmt"
+	"log"
+)
+
+// Метод сохранения результата попытки опроса источника. При успехе обновляются время последней успешной загрузки
+// и число статей, счетчик ошибок подряд сбрасывается; при ошибке счетчик увеличивается и сохраняется текст ошибки.
+func (s *Storage) RecordFetch(r models.FetchResult) error {
+	ok := r.Err == nil
+	lastError := ""
+	if !ok {
+		lastError = r.Err.Error()
+	}
+	_, err := s.Db.Exec(context.Background(), `INSERT INTO source_health AS h (source_id,last_attempt,last_success,
+	consecutive_failures,last_error,last_error_at,http_status,latency_ms,items_last_fetch,total_fetches,total_failures)
+	VALUES ($1,$2,CASE WHEN $3 THEN $2 ELSE 0 END,CASE WHEN $3 THEN 0 ELSE 1 END,$4,CASE WHEN $3 THEN 0 ELSE $2 END,
+	$5,$6,$7,1,CASE WHEN $3 THEN 0 ELSE 1 END)
+	ON CONFLICT (source_id) DO UPDATE SET
+	last_attempt = EXCLUDED.last_attempt,
+	last_success = CASE WHEN $3 THEN EXCLUDED.last_attempt ELSE h.last_success END,
+	consecutive_failures = CASE WHEN $3 THEN 0 ELSE h.consecutive_failures + 1 END,
+	last_error = CASE WHEN $3 THEN h.last_error ELSE EXCLUDED.last_error END,
+	last_error_at = CASE WHEN $3 THEN h.last_error_at ELSE EXCLUDED.last_attempt END,
+	http_status = EXCLUDED.http_status,
+	latency_ms = EXCLUDED.latency_ms,
+	items_last_fetch = CASE WHEN $3 THEN EXCLUDED.items_last_fetch ELSE h.items_last_fetch END,
+	total_fetches = h.total_fetches + 1,
+	total_failures = h.total_failures + CASE WHEN $3 THEN 0 ELSE 1 END;`,
+		r.SourceID, r.Time, ok, lastError, r.HTTPStatus, r.LatencyMs, r.Items)
+	if err != nil {
+		log.Printf("Cant save source health in database! %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// Метод получения состояния опроса всех источников. Сначала возвращаются источники с наибольшим
+// числом ошибок подряд.
+func (s *Storage) ListSourceHealth() ([]models.SourceHealth, error) {
+	rows, err := s.Db.Query(context.Background(), `SELECT s.id,s.url,s.title,s.enabled,
+	COALESCE(h.last_attempt,0),COALESCE(h.last_success,0),COALESCE(h.consecutive_failures,0),COALESCE(h.last_error,''),
+	COALESCE(h.last_error_at,0),COALESCE(h.http_status,0),COALESCE(h.latency_ms,0),COALESCE(h.items_last_fetch,0),
+	COALESCE(h.total_fetches,0),COALESCE(h.total_failures,0)
+	FROM sources s LEFT JOIN source_health h ON h.source_id = s.id
+	ORDER BY COALESCE(h.consecutive_failures,0) DESC, s.id;`)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	health := []models.SourceHealth{}
+	for rows.Next() {
+		h := models.SourceHealth{}
+		err = rows.Scan(
+			&h.SourceID,
+			&h.URL,
+			&h.Title,
+			&h.Enabled,
+			&h.LastAttempt,
+			&h.LastSuccess,
+			&h.ConsecutiveFailures,
+			&h.LastError,
+			&h.LastErrorAt,
+			&h.HTTPStatus,
+			&h.LatencyMs,
+			&h.ItemsLastFetch,
+			&h.TotalFetches,
+			&h.TotalFailures,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		health = append(health, h)
+	}
+	return health, rows.Err()
+}
@@ -0,0 +1,240 @@
+package postgress
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// Миграции схемы БД: пары файлов NNNN_name.up.sql и NNNN_name.down.sql, применяются по возрастанию номера
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Ключ рекомендательной блокировки Postgres, под которой выполняются миграции: параллельно запущенные
+// экземпляры сервиса ждут, пока миграции применит первый из них
+const migrationLock = 36_0000_0001
+
+// Миграция схемы
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+// Состояние миграции
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt int64 //время применения, 0 - не применена
+}
+
+// Функция чтения миграций из каталога fsys. Возвращает миграции по возрастанию номера.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*migration)
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: name must end with .up.sql or .down.sql", base)
+		}
+		num, name, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: name must start with a positive version number", base)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("migration %d has different names: %s and %s", version, m.name, name)
+		}
+		if direction == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.version, m.name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// Встроенные миграции
+func embeddedMigrations() ([]migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return loadMigrations(sub)
+}
+
+// Метод применения всех непримененных миграций. Возвращает число примененных миграций.
+func (s *Storage) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn, done map[int]int64) error {
+		for _, m := range migrations {
+			if _, ok := done[m.version]; ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.up, `INSERT INTO schema_migrations (version,name,applied_at)
+			VALUES ($1,$2,$3);`, m.version, m.name, time.Now().Unix())
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+			log.Printf("Migration %d_%s applied\n", m.version, m.name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Метод отката steps последних примененных миграций. Возвращает число отмененных миграций.
+func (s *Storage) MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+	reverted := 0
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn, done map[int]int64) error {
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.version]; !ok {
+				continue
+			}
+			if m.down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", m.version, m.name)
+			}
+			err := runMigration(ctx, conn, m.down, `DELETE FROM schema_migrations WHERE version = $1;`, m.version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+			}
+			log.Printf("Migration %d_%s reverted\n", m.version, m.name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Метод получения состояния встроенных миграций. Примененные миграции, неизвестные этой версии сервиса,
+// также возвращаются (без имени).
+func (s *Storage) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var status []MigrationStatus
+	err = s.withMigrationLock(ctx, func(conn *pgxpool.Conn, done map[int]int64) error {
+		for _, m := range migrations {
+			at, ok := done[m.version]
+			status = append(status, MigrationStatus{Version: m.version, Name: m.name, Applied: ok, AppliedAt: at})
+			delete(done, m.version)
+		}
+		for version, at := range done {
+			status = append(status, MigrationStatus{Version: version, Applied: true, AppliedAt: at})
+		}
+		return nil
+	})
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, err
+}
+
+// Выполнение fn на отдельном соединении под рекомендательной блокировкой. fn получает номера
+// примененных миграций со временем их применения.
+func (s *Storage) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn, done map[int]int64) error) error {
+	conn, err := s.Db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1);`, migrationLock); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLock)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at BIGINT NOT NULL);`)
+	if err != nil {
+		return err
+	}
+	rows, err := conn.Query(ctx, `SELECT version,applied_at FROM schema_migrations;`)
+	if err != nil {
+		return err
+	}
+	done := make(map[int]int64)
+	for rows.Next() {
+		var version int
+		var at int64
+		if err = rows.Scan(&version, &at); err != nil {
+			rows.Close()
+			return fmt.Errorf("unable scan row: %w", err)
+		}
+		done[version] = at
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	return fn(conn, done)
+}
+
+// Выполнение скрипта миграции и запроса записи о ней в одной транзакции
+func runMigration(ctx context.Context, conn *pgxpool.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	//скрипт из нескольких команд выполняется без параметров по простому протоколу
+	if _, err = tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if _, err = tx.Exec(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
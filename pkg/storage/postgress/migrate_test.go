@@ -0,0 +1,141 @@
+package postgress
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"testing/fstest"
+
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		versions []int
+		wantErr  bool
+	}{
+		{
+			name: "по возрастанию номера",
+			fsys: fstest.MapFS{
+				"0010_search.up.sql":   file("ALTER TABLE news ADD COLUMN tsv tsvector;"),
+				"0010_search.down.sql": file("ALTER TABLE news DROP COLUMN tsv;"),
+				"0002_two.up.sql":      file("SELECT 2;"),
+				"0001_init.up.sql":     file("SELECT 1;"),
+				"0001_init.down.sql":   file("SELECT -1;"),
+			},
+			versions: []int{1, 2, 10},
+		},
+		{name: "нет up", fsys: fstest.MapFS{"0001_init.down.sql": file("")}, wantErr: true},
+		{name: "нет номера", fsys: fstest.MapFS{"init.up.sql": file("SELECT 1;")}, wantErr: true},
+		{name: "нет направления", fsys: fstest.MapFS{"0001_init.sql": file("SELECT 1;")}, wantErr: true},
+		{
+			name: "разные имена",
+			fsys: fstest.MapFS{
+				"0001_init.up.sql":    file("SELECT 1;"),
+				"0001_schema.up.sql":  file("SELECT 1;"),
+				"0001_init.down.sql":  file("SELECT 1;"),
+				"0001_other.down.sql": file("SELECT 1;"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.versions) {
+				t.Fatalf("loadMigrations() returned %d migrations, want %d", len(got), len(tt.versions))
+			}
+			for i, m := range got {
+				if m.version != tt.versions[i] {
+					t.Errorf("migration %d version = %d, want %d", i, m.version, tt.versions[i])
+				}
+			}
+			if len(got) == 3 && (got[0].down != "SELECT -1;" || got[1].down != "" || got[2].name != "search") {
+				t.Errorf("loadMigrations() = %+v", got)
+			}
+		})
+	}
+}
+
+// Тест проверяет, что встроенные миграции читаются и у каждой есть скрипт отката
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].version != 1 {
+		t.Fatalf("embeddedMigrations() = %+v, want migrations starting with 1", migrations)
+	}
+	for _, m := range migrations {
+		if m.down == "" {
+			t.Errorf("migration %d_%s has no down script", m.version, m.name)
+		}
+	}
+}
+
+// Тест проверяет перевод на миграции базы, созданной из shema.sql первой версии: миграции применяются
+// к существующей таблице news без потери статей, после чего статьи сохраняются в новую схему, а миграции
+// откатываются. Схема создается в отдельной схеме Postgres тестовой БД.
+func TestMigrateUp_Baseline(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+	defer db.Db.Close()
+
+	ctx := context.Background()
+	schema := "migrate_test_" + strconv.Itoa(rand.Intn(999999999))
+	_, err = db.Db.Exec(ctx, `CREATE SCHEMA `+schema+`;`)
+	require.NoError(t, err)
+	defer db.Db.Exec(ctx, `DROP SCHEMA `+schema+` CASCADE;`)
+
+	cfg := db.Db.Config().Copy()
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.ConnectConfig(ctx, cfg)
+	require.NoError(t, err)
+	defer pool.Close()
+	s := &Storage{Db: pool}
+
+	baseline, err := os.ReadFile("testdata/baseline.sql")
+	require.NoError(t, err)
+	_, err = pool.Exec(ctx, string(baseline))
+	require.NoError(t, err)
+	var oldID int
+	err = pool.QueryRow(ctx, `INSERT INTO news (title,content,preview,published,link)
+	VALUES ('Old','Old content','Old...',1729584000,'https://example.com/old') RETURNING id;`).Scan(&oldID)
+	require.NoError(t, err)
+
+	migrations, err := embeddedMigrations()
+	require.NoError(t, err)
+	applied, err := s.MigrateUp(ctx)
+	require.NoError(t, err)
+	require.Equal(t, len(migrations), applied)
+
+	old, err := s.GetDetailedNews(oldID)
+	require.NoError(t, err)
+	require.Equal(t, "Old", old.Title)
+
+	_, err = s.AddNews([]models.NewsFullDetailed{{Title: "New", Content: "New content", Published: 1729584001,
+		Link: "https://example.com/new", GUID: "new", Categories: []string{"go"}}})
+	require.NoError(t, err)
+	found, err := s.FilterNewsByContent("content", false)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+
+	applied, err = s.MigrateUp(ctx)
+	require.NoError(t, err)
+	require.Zero(t, applied)
+
+	reverted, err := s.MigrateDown(ctx, len(migrations))
+	require.NoError(t, err)
+	require.Equal(t, len(migrations), reverted)
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS websub_subscriptions, source_health, news_revisions, news, sources;
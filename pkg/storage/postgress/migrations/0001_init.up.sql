@@ -0,0 +1,77 @@
+-- Начальная схема
+CREATE TABLE IF NOT EXISTS sources (
+  id BIGSERIAL PRIMARY KEY,
+  url TEXT NOT NULL UNIQUE,
+  title TEXT NOT NULL DEFAULT '',
+  group_name TEXT NOT NULL DEFAULT '',
+  interval INTEGER NOT NULL DEFAULT 0,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  options JSONB NOT NULL DEFAULT '{}',
+  updated_at BIGINT NOT NULL DEFAULT 0
+);
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS group_name TEXT NOT NULL DEFAULT '';
+
+-- Таблица news в том виде, в котором ее создавал shema.sql первой версии сервиса. Столбцы, появившиеся
+-- позже, добавляются через ADD COLUMN IF NOT EXISTS, поэтому миграция применяется и к пустой базе,
+-- и к базе, созданной из shema.sql любой версии.
+CREATE TABLE IF NOT EXISTS news (
+  id BIGSERIAL PRIMARY KEY,
+  title TEXT NOT NULL,
+  content TEXT ,
+  preview TEXT ,
+  published BIGINT,
+  link TEXT NOT NULL UNIQUE
+);
+ALTER TABLE news
+  ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS full_content TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS original_link TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS author TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS categories TEXT[],
+  ADD COLUMN IF NOT EXISTS guid TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS enclosures JSONB,
+  ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS published_source TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS source_id BIGINT REFERENCES sources (id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS fingerprint BIGINT,
+  ADD COLUMN IF NOT EXISTS canonical_id BIGINT REFERENCES news (id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS content_hash TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS updated_at BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS news_published_idx ON news (published);
+CREATE INDEX IF NOT EXISTS news_canonical_id_idx ON news (canonical_id);
+CREATE UNIQUE INDEX IF NOT EXISTS news_guid_idx ON news (guid) WHERE guid <> '';
+
+CREATE TABLE IF NOT EXISTS news_revisions (
+  id BIGSERIAL PRIMARY KEY,
+  news_id BIGINT NOT NULL REFERENCES news (id) ON DELETE CASCADE,
+  revision INTEGER NOT NULL,
+  title TEXT NOT NULL,
+  content TEXT NOT NULL DEFAULT '',
+  content_html TEXT NOT NULL DEFAULT '',
+  created_at BIGINT NOT NULL,
+  UNIQUE (news_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS source_health (
+  source_id BIGINT PRIMARY KEY REFERENCES sources (id) ON DELETE CASCADE,
+  last_attempt BIGINT NOT NULL DEFAULT 0,
+  last_success BIGINT NOT NULL DEFAULT 0,
+  consecutive_failures INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT NOT NULL DEFAULT '',
+  last_error_at BIGINT NOT NULL DEFAULT 0,
+  http_status INTEGER NOT NULL DEFAULT 0,
+  latency_ms BIGINT NOT NULL DEFAULT 0,
+  items_last_fetch INTEGER NOT NULL DEFAULT 0,
+  total_fetches BIGINT NOT NULL DEFAULT 0,
+  total_failures BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS websub_subscriptions (
+  source_id BIGINT PRIMARY KEY REFERENCES sources (id) ON DELETE CASCADE,
+  hub TEXT NOT NULL,
+  topic TEXT NOT NULL,
+  secret TEXT NOT NULL,
+  state TEXT NOT NULL DEFAULT 'pending',
+  lease_expires BIGINT NOT NULL DEFAULT 0,
+  updated_at BIGINT NOT NULL DEFAULT 0
+);
@@ -0,0 +1,8 @@
+-- Полнотекстовый поиск по заголовку (вес A) и тексту (вес B) статьи с русской и английской морфологией
+ALTER TABLE news ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('russian', COALESCE(content, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(content, '')), 'B')
+) STORED;
+CREATE INDEX IF NOT EXISTS news_search_idx ON news USING GIN (search);
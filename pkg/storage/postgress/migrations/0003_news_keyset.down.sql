@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS news_published_idx ON news (published);
+DROP INDEX IF EXISTS news_published_id_idx;
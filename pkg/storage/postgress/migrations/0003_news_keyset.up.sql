@@ -0,0 +1,3 @@
+-- Индекс для вывода списка новостей по курсору (published, id)
+CREATE INDEX IF NOT EXISTS news_published_id_idx ON news (published, id);
+DROP INDEX IF EXISTS news_published_idx;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS news_source_guid_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS news_guid_idx ON news (guid) WHERE guid <> '';
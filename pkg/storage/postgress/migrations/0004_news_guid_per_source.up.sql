@@ -0,0 +1,3 @@
+-- GUID уникален только внутри источника: разные ленты могут использовать одинаковые GUID
+DROP INDEX IF EXISTS news_guid_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS news_source_guid_idx ON news (COALESCE(source_id, 0), guid) WHERE guid <> '';
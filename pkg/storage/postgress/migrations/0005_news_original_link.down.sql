@@ -0,0 +1 @@
+DROP INDEX IF EXISTS news_original_link_idx;
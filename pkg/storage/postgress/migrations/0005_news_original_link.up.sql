@@ -0,0 +1,2 @@
+-- Поиск сохраненных статей по исходной ссылке из источника
+CREATE INDEX IF NOT EXISTS news_original_link_idx ON news (original_link);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS news_search_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS search;
+ALTER TABLE news ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('russian', COALESCE(content, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(content, '')), 'B')
+) STORED;
+CREATE INDEX news_search_idx ON news USING GIN (search);
@@ -0,0 +1,13 @@
+-- Анонс статьи (вес C) тоже участвует в полнотекстовом поиске. Выражение генерируемого столбца
+-- не изменяется, поэтому столбец и индекс создаются заново.
+DROP INDEX IF EXISTS news_search_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS search;
+ALTER TABLE news ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+  setweight(to_tsvector('russian', COALESCE(content, '')), 'B') ||
+  setweight(to_tsvector('english', COALESCE(content, '')), 'B') ||
+  setweight(to_tsvector('russian', COALESCE(preview, '')), 'C') ||
+  setweight(to_tsvector('english', COALESCE(preview, '')), 'C')
+) STORED;
+CREATE INDEX news_search_idx ON news USING GIN (search);
@@ -0,0 +1,7 @@
+ALTER TABLE news ADD COLUMN IF NOT EXISTS original_link TEXT NOT NULL DEFAULT '';
+UPDATE news SET original_link = link, link = COALESCE(link_key, link);
+DROP INDEX IF EXISTS news_link_idx;
+CREATE INDEX IF NOT EXISTS news_original_link_idx ON news (original_link);
+ALTER TABLE news ADD CONSTRAINT news_link_key UNIQUE (link);
+DROP INDEX IF EXISTS news_link_key_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS link_key;
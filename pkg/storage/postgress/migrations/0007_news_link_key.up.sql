@@ -0,0 +1,12 @@
+-- Ссылка статьи хранится в том виде, в котором она загружается и показывается читателю, а уникальность
+-- проверяется по каноническому ключу link_key. Ключом сохраненных статей становится их прежняя ссылка
+-- (уже приведенная к каноническому виду), ссылкой - исходная ссылка из источника. Ключ заполняется при
+-- сохранении статей; статьи, добавленные без ключа в обход AddNews, в проверке уникальности не участвуют.
+ALTER TABLE news ADD COLUMN IF NOT EXISTS link_key TEXT;
+UPDATE news SET link_key = link;
+CREATE UNIQUE INDEX IF NOT EXISTS news_link_key_idx ON news (link_key);
+ALTER TABLE news DROP CONSTRAINT IF EXISTS news_link_key;
+UPDATE news SET link = original_link WHERE original_link <> '';
+DROP INDEX IF EXISTS news_original_link_idx;
+ALTER TABLE news DROP COLUMN IF EXISTS original_link;
+CREATE INDEX IF NOT EXISTS news_link_idx ON news (link);
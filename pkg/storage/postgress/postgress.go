@@ -1,6 +1,8 @@
 package postgress
 
 import (
+	"Skillfactory/36-GoNews/pkg/canonical"
+	"Skillfactory/36-GoNews/pkg/dedup"
 	models "Skillfactory/36-GoNews/pkg/storage/models"
 	"context"
 	"errors"
@@ -8,8 +10,9 @@ import (
 	"log"
 	"os"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/joho/godotenv"
 )
@@ -72,7 +75,9 @@ func (s *Storage) GetDetailedNews(id int) (models.NewsFullDetailed, error) {
 	}
 
 	q := strconv.Itoa(id)
-	rows, err := s.Db.Query(context.Background(), `SELECT id,title,content,published,link FROM news WHERE id = $1`, q)
+	rows, err := s.Db.Query(context.Background(), `SELECT id,title,content,content_html,full_content,published,link,COALESCE(link_key,''),author,
+	categories,guid,enclosures,image_url,published_source,COALESCE(source_id,0),COALESCE(canonical_id,0),updated_at
+	FROM news WHERE id = $1`, q)
 	if err != nil {
 		log.Printf("Cant read data from database: %v\n", err)
 		return models.NewsFullDetailed{}, err
@@ -85,8 +90,20 @@ func (s *Storage) GetDetailedNews(id int) (models.NewsFullDetailed, error) {
 			&news.ID,
 			&news.Title,
 			&news.Content,
+			&news.ContentHTML,
+			&news.FullContent,
 			&news.Published,
 			&news.Link,
+			&news.LinkKey,
+			&news.Author,
+			&news.Categories,
+			&news.GUID,
+			&news.Enclosures,
+			&news.ImageURL,
+			&news.PublishedSource,
+			&news.SourceID,
+			&news.CanonicalID,
+			&news.Updated,
 		)
 		if err != nil {
 			return models.NewsFullDetailed{}, fmt.Errorf("unable scan row: %w", err)
@@ -109,7 +126,7 @@ func (s *Storage) GetNewsList(n int) ([]models.NewsFullDetailed, error) {
 	}
 	q := strconv.Itoa(n)
 
-	rows, err := s.Db.Query(context.Background(), `SELECT id,title,preview,published,link FROM news ORDER BY published DESC LIMIT $1`, q)
+	rows, err := s.Db.Query(context.Background(), `SELECT id,title,preview,published,link,author,image_url FROM news ORDER BY published DESC LIMIT $1`, q)
 	if err != nil {
 		log.Printf("Cant read data from database: %v\n", err)
 		return nil, err
@@ -126,6 +143,8 @@ func (s *Storage) GetNewsList(n int) ([]models.NewsFullDetailed, error) {
 			&new.Preview,
 			&new.Published,
 			&new.Link,
+			&new.Author,
+			&new.ImageURL,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("unable scan row: %w", err)
@@ -136,13 +155,17 @@ func (s *Storage) GetNewsList(n int) ([]models.NewsFullDetailed, error) {
 	return news, nil
 }
 
-// Метод для возврата списка новостей с пагинацией
-func (s *Storage) GetNewsListWithPagination(n, offset, limit int) ([]models.NewsFullDetailed, error) {
-	query := `WITH subquery AS (SELECT id, title, preview, published, link FROM news ORDER BY published LIMIT $1)
-	SELECT id, title, preview, published, link FROM subquery OFFSET $2 LIMIT $3`
-	rows, err := s.Db.Query(context.Background(), query, n, offset, limit)
+// Метод для возврата списка новостей с пагинацией. Новости упорядочены от новых к старым, n - ограничение
+// общего числа новостей. collapse - не возвращать почти дубликаты других статей.
+func (s *Storage) GetNewsListWithPagination(n, offset, limit int, collapse bool) ([]models.NewsFullDetailed, error) {
+	query := `WITH subquery AS (SELECT id, title, preview, published, link, author, image_url FROM news
+	WHERE (NOT $4 OR canonical_id IS NULL) ORDER BY published DESC, id DESC LIMIT $1)
+	SELECT id, title, preview, published, link, author, image_url FROM subquery
+	ORDER BY published DESC, id DESC OFFSET $2 LIMIT $3`
+	rows, err := s.Db.Query(context.Background(), query, n, offset, limit, collapse)
 	if err != nil {
 		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -156,6 +179,8 @@ func (s *Storage) GetNewsListWithPagination(n, offset, limit int) ([]models.News
 			&new.Preview,
 			&new.Published,
 			&new.Link,
+			&new.Author,
+			&new.ImageURL,
 		)
 		if err != nil {
 			return news, fmt.Errorf("unable scan row: %w", err)
@@ -165,6 +190,55 @@ func (s *Storage) GetNewsListWithPagination(n, offset, limit int) ([]models.News
 	return news, nil
 }
 
+// Метод для возврата списка новостей от новых к старым с выводом по курсору. cursor - позиция, от которой
+// выбирается страница (nil - первая страница). Возвращает не больше limit новостей и признак наличия новостей
+// за пределами страницы в направлении курсора.
+func (s *Storage) GetNewsListByCursor(cursor *models.Cursor, limit int, collapse bool) ([]models.NewsFullDetailed, bool, error) {
+	return s.listByCursor(`NOT $1 OR canonical_id IS NULL`, []interface{}{collapse}, cursor, limit)
+}
+
+// Метод выборки страницы новостей, отобранных условием where с параметрами args, по курсору от новых к старым
+func (s *Storage) listByCursor(where string, args []interface{}, cursor *models.Cursor, limit int) ([]models.NewsFullDetailed, bool, error) {
+	var key []interface{}
+	if cursor != nil {
+		key = []interface{}{cursor.Published, cursor.ID}
+	}
+	page, pageArgs := keysetPage([]string{"published", "id"}, key, cursor, limit, len(args)+1)
+	query := `WITH found AS (SELECT id, title, preview, published, link, author, image_url FROM news
+	WHERE ` + where + `),
+	page AS (SELECT * FROM found ` + page + `)
+	SELECT id, title, preview, published, link, author, image_url FROM page ORDER BY published DESC, id DESC;`
+	rows, err := s.Db.Query(context.Background(), query, append(args, pageArgs...)...)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	news := []models.NewsFullDetailed{}
+	for rows.Next() {
+		new := models.NewsFullDetailed{}
+		err = rows.Scan(
+			&new.ID,
+			&new.Title,
+			&new.Preview,
+			&new.Published,
+			&new.Link,
+			&new.Author,
+			&new.ImageURL,
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("unable scan row: %w", err)
+		}
+		news = append(news, new)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, false, err
+	}
+	news, more := trimPage(news, cursor, limit)
+	return news, more, nil
+}
+
 // Функция для создания превью новости
 func PrevieMaker(detailNews string) string {
 	var preview []rune
@@ -178,82 +252,251 @@ func PrevieMaker(detailNews string) string {
 	return string(preview) + "..."
 }
 
-// Метод добавления статьи в БД. На вход принимает слайс объектов, возвращает ошибку, при наличии.
-func (s *Storage) AddNews(news []models.NewsFullDetailed) error {
+// Окно поиска почти одинаковых статей: статьи, опубликованные раньше на это число секунд, не сравниваются
+const duplicateWindow = 3 * 24 * 60 * 60
+
+// Отпечаток сохраненной статьи
+type fingerprint struct {
+	id int
+	fp uint64
+}
+
+// Метод добавления статей в БД. На вход принимает слайс объектов, возвращает число добавленных, обновленных
+// и пропущенных статей. Пакет сохраняется в одной транзакции: сохраненные статьи ищутся одним запросом,
+// запросы вставки и обновления отправляются одним пакетом. Для каждой статьи рассчитывается отпечаток,
+// почти одинаковые статьи связываются с ранее сохраненной исходной статьей через canonical_id.
+// Уже сохраненные статьи (по каноническому ключу ссылки или GUID того же источника) с измененным заголовком или текстом обновляются, предыдущая
+// версия сохраняется в news_revisions. Статьи, ссылка или GUID которых уже заняты (повтор внутри пакета
+// или параллельное добавление), пропускаются и не прерывают сохранение остальных.
+func (s *Storage) AddNews(news []models.NewsFullDetailed) (models.IngestStats, error) {
+	var stats models.IngestStats
+	if len(news) == 0 {
+		return stats, nil
+	}
+	since := news[0].Published
+	keys := make([]string, 0, len(news))
+	var guids []guidKey
+	for i, n := range news {
+		if n.Published < since {
+			since = n.Published
+		}
+		if n.LinkKey == "" {
+			news[i].LinkKey = canonical.URL(n.Link)
+		}
+		keys = append(keys, news[i].LinkKey)
+		if n.GUID != "" {
+			guids = append(guids, guidKey{n.SourceID, n.GUID})
+		}
+	}
+
+	ctx := context.Background()
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		log.Printf("Cant add data in database! %v\n", err)
+		return stats, err
+	}
+	defer tx.Rollback(ctx)
+
+	byKey, byGUID, err := findStoredNews(ctx, tx, keys, guids)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return stats, err
+	}
+
+	now := time.Now().Unix()
+	//старая или не датированная статья в пакете не расширяет выборку отпечатков за пределы окна
+	if since < now-duplicateWindow {
+		since = now - duplicateWindow
+	}
+	known, err := recentFingerprints(ctx, tx, since-duplicateWindow)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return stats, err
+	}
+	batch := &pgx.Batch{}
+	var fresh []models.NewsFullDetailed
 	for _, n := range news {
 		n.Preview = PrevieMaker(n.Content)
-		_, err := s.Db.Exec(context.Background(), `INSERT INTO news 
-		(title,content,preview,published,link) VALUES ($1,$2,$3,$4,$5);`,
-			n.Title, n.Content, n.Preview, n.Published, n.Link)
+		n.Fingerprint = int64(dedup.Fingerprint(n.Title, n.Content))
+		hash := contentHash(n.Title, n.Content, n.ContentHTML)
+
+		stored, ok := byKey[n.LinkKey]
+		if !ok && n.GUID != "" {
+			stored, ok = byGUID[guidKey{n.SourceID, n.GUID}]
+		}
+		if !ok {
+			fresh = append(fresh, n)
+			continue
+		}
+		if stored.hash == hash {
+			stats.Skipped++
+			continue
+		}
+		queueUpdate(batch, stored, n, hash, now)
+		stats.Updated++
+		//повторная статья в пакете сравнивается с только что поставленной в очередь версией
+		stored.hash, stored.title, stored.content, stored.contentHTML, stored.updated = hash, n.Title, n.Content, n.ContentHTML, now
+		byKey[n.LinkKey] = stored
+		if n.GUID != "" {
+			byGUID[guidKey{n.SourceID, n.GUID}] = stored
+		}
+	}
+	updates := batch.Len()
+
+	//идентификаторы новых статей выделяются заранее, чтобы почти дубликаты внутри пакета
+	//связывались с исходной статьей из того же пакета
+	ids, err := reserveNewsIDs(ctx, tx, len(fresh))
+	if err != nil {
+		log.Printf("Cant add data in database! %v\n", err)
+		return stats, err
+	}
+	for i, n := range fresh {
+		fp := uint64(n.Fingerprint)
+		n.CanonicalID = 0
+		for _, k := range known {
+			if dedup.Similar(fp, k.fp) {
+				n.CanonicalID = k.id
+				break
+			}
+		}
+		//исходная статья из пакета может быть пропущена из-за конфликта, поэтому canonical_id
+		//указывает только на существующую статью
+		batch.Queue(`INSERT INTO news 
+		(id,title,content,preview,published,link,author,categories,guid,enclosures,image_url,published_source,full_content,
+		content_html,source_id,fingerprint,canonical_id,link_key,content_hash,updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,NULLIF($15::BIGINT,0),$16,
+		(SELECT id FROM news WHERE id = NULLIF($17::BIGINT,0)),$18,$19,$20)
+		ON CONFLICT DO NOTHING;`,
+			ids[i], n.Title, n.Content, n.Preview, n.Published, n.Link, n.Author, n.Categories, n.GUID, n.Enclosures,
+			n.ImageURL, n.PublishedSource, n.FullContent, n.ContentHTML, n.SourceID, n.Fingerprint, n.CanonicalID,
+			n.LinkKey, contentHash(n.Title, n.Content, n.ContentHTML), now)
+		if n.CanonicalID == 0 && fp != 0 {
+			known = append(known, fingerprint{id: ids[i], fp: fp})
+		}
+	}
+
+	br := tx.SendBatch(ctx, batch)
+	for i := 0; i < batch.Len(); i++ {
+		tag, err := br.Exec()
 		if err != nil {
+			br.Close()
 			log.Printf("Cant add data in database! %v\n", err)
-			return err
+			return models.IngestStats{}, err
 		}
+		if i < updates {
+			continue
+		}
+		if tag.RowsAffected() == 0 {
+			stats.Skipped++
+		} else {
+			stats.Inserted++
+		}
+	}
+	if err = br.Close(); err != nil {
+		log.Printf("Cant add data in database! %v\n", err)
+		return models.IngestStats{}, err
 	}
-	return nil
+	if err = tx.Commit(ctx); err != nil {
+		log.Printf("Cant add data in database! %v\n", err)
+		return models.IngestStats{}, err
+	}
+	return stats, nil
 }
 
-// Метод для выборки из БД новостей с учетом заданного фильтра
-func (s *Storage) FilterNewsByContent(filter string) ([]models.NewsFullDetailed, error) {
-	query := `SELECT id,title,preview,published,link FROM news WHERE 
-              LOWER(content) LIKE $1 OR LOWER(title) LIKE $1 OR LOWER(preview) LIKE $1 ORDER BY published DESC;`
-	rows, err := s.Db.Query(context.Background(), query, "%"+strings.ToLower(filter)+"%")
+// Выделение n идентификаторов статей из последовательности таблицы news
+func reserveNewsIDs(ctx context.Context, tx pgx.Tx, n int) ([]int, error) {
+	ids := make([]int, 0, n)
+	if n == 0 {
+		return ids, nil
+	}
+	rows, err := tx.Query(ctx, `SELECT nextval(pg_get_serial_sequence('news','id')) FROM generate_series(1,$1);`, n)
 	if err != nil {
-		log.Printf("cant read filtered data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Получение отпечатков исходных (не являющихся дубликатами) статей, опубликованных после since
+func recentFingerprints(ctx context.Context, tx pgx.Tx, since int64) ([]fingerprint, error) {
+	rows, err := tx.Query(ctx, `SELECT id,fingerprint FROM news
+	WHERE published >= $1 AND canonical_id IS NULL AND fingerprint IS NOT NULL AND fingerprint <> 0;`, since)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
-	news := []models.NewsFullDetailed{}
+	var fps []fingerprint
 	for rows.Next() {
-		new := models.NewsFullDetailed{}
-		err = rows.Scan(
-			&new.ID,
-			&new.Title,
-			&new.Preview,
-			&new.Published,
-			&new.Link,
-		)
-		if err != nil {
+		var f fingerprint
+		var fp int64
+		if err = rows.Scan(&f.id, &fp); err != nil {
 			return nil, fmt.Errorf("unable scan row: %w", err)
 		}
-		news = append(news, new)
+		f.fp = uint64(fp)
+		fps = append(fps, f)
 	}
-	return news, nil
+	return fps, rows.Err()
 }
 
-// Метод для выборки из БД новостей с учетом заданного фильтра и пагинацией
-func (s *Storage) FilterNewsByContentWithPagination(filter string, offset, limit int) ([]models.NewsFullDetailed, error) {
-	query := `WITH subquery AS (SELECT id, title, preview, published, link FROM news WHERE LOWER(content) LIKE $1 OR LOWER(title) LIKE $1
-	OR LOWER(preview) LIKE $1 ORDER BY published DESC) SELECT id, title, preview, published, link FROM subquery OFFSET $2 LIMIT $3;`
-	rows, err := s.Db.Query(context.Background(), query, ("%" + strings.ToLower(filter) + "%"), offset, limit)
+// Метод проверки, какие из ссылок уже сохранены в БД. Ссылка считается сохраненной, если она совпадает
+// со ссылкой статьи или с ее каноническим ключом (ключ мог быть взят из канонической ссылки со страницы).
+// Возвращает множество сохраненных ссылок.
+func (s *Storage) ExistingLinks(links []string) (map[string]bool, error) {
+	rows, err := s.Db.Query(context.Background(), `SELECT l FROM unnest($1::TEXT[]) AS l
+	WHERE EXISTS (SELECT 1 FROM news WHERE link = l) OR EXISTS (SELECT 1 FROM news WHERE link_key = l);`, links)
 	if err != nil {
-		log.Printf("cant read filtered data from database: %v\n", err)
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	news := []models.NewsFullDetailed{}
+	existing := make(map[string]bool)
 	for rows.Next() {
-		new := models.NewsFullDetailed{}
-		err = rows.Scan(
-			&new.ID,
-			&new.Title,
-			&new.Preview,
-			&new.Published,
-			&new.Link,
-		)
-		if err != nil {
+		var link string
+		if err = rows.Scan(&link); err != nil {
 			return nil, fmt.Errorf("unable scan row: %w", err)
 		}
-		news = append(news, new)
+		existing[link] = true
 	}
-	return news, nil
+	return existing, rows.Err()
+}
+
+// Метод полнотекстового поиска новостей по заголовку, тексту и анонсу. Возвращает статьи по убыванию
+// релевантности с фрагментами текста, в которых найденные слова выделены тегом <mark>. Пустой фильтр
+// возвращает все статьи по дате публикации.
+// collapse - не возвращать почти дубликаты других статей.
+func (s *Storage) FilterNewsByContent(filter string, collapse bool) ([]models.NewsFullDetailed, error) {
+	return s.searchNews(filter, collapse, func(int) (string, []interface{}) {
+		return `ORDER BY rank DESC, published DESC, id DESC`, nil
+	})
+}
+
+// Метод полнотекстового поиска новостей с пагинацией
+func (s *Storage) FilterNewsByContentWithPagination(filter string, offset, limit int, collapse bool) ([]models.NewsFullDetailed, error) {
+	return s.searchNews(filter, collapse, func(n int) (string, []interface{}) {
+		return fmt.Sprintf(`ORDER BY rank DESC, published DESC, id DESC OFFSET $%d LIMIT $%d`, n, n+1), []interface{}{offset, limit}
+	})
+}
+
+// Метод для выборки из БД новостей с заданной датой публикации с выводом по курсору. Как и GetNewsListByCursor,
+// возвращает не больше limit новостей и признак наличия новостей за пределами страницы в направлении курсора.
+func (s *Storage) FilterNewsByPublishedByCursor(filter int, cursor *models.Cursor, limit int, collapse bool) ([]models.NewsFullDetailed, bool, error) {
+	return s.listByCursor(`published = $1 AND (NOT $2 OR canonical_id IS NULL)`, []interface{}{filter, collapse}, cursor, limit)
 }
 
-// Метод для выборки из БД новостей с учетом заданного фильтра по дате публикации
-func (s *Storage) FilterNewsByPublished(filter int) ([]models.NewsFullDetailed, error) {
+// Метод для выборки из БД новостей с учетом заданного фильтра по дате публикации. collapse - не возвращать почти дубликаты.
+func (s *Storage) FilterNewsByPublished(filter int, collapse bool) ([]models.NewsFullDetailed, error) {
 	q := strconv.Itoa(filter)
-	rows, err := s.Db.Query(context.Background(), `SELECT id,title,preview,published,link FROM news
-	 WHERE published = $1;`, q)
+	rows, err := s.Db.Query(context.Background(), `SELECT id,title,preview,published,link,author,image_url FROM news
+	 WHERE published = $1 AND (NOT $2 OR canonical_id IS NULL);`, q, collapse)
 	if err != nil {
 		log.Printf("cant read filtered data from database: %v\n", err)
 	}
@@ -269,6 +512,8 @@ func (s *Storage) FilterNewsByPublished(filter int) ([]models.NewsFullDetailed,
 			&new.Preview,
 			&new.Published,
 			&new.Link,
+			&new.Author,
+			&new.ImageURL,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("unable scan row: %w", err)
@@ -8,7 +8,9 @@ import (
 	"math/rand"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/stretchr/testify/require"
@@ -58,10 +60,13 @@ func TestAddNews(t *testing.T) {
 		},
 	}
 
-	err = db.AddNews(news)
+	stats, err := db.AddNews(news)
 	if err != nil {
 		t.Fatalf("Error adding NewsFullDetailed in database - %v", err)
 	}
+	if stats.Inserted != 2 {
+		t.Errorf("AddNews() inserted %d, want 2", stats.Inserted)
+	}
 	//Очищаем базу от тестовой записи
 	_, err = db.Db.Exec(context.Background(), initDataSqlQuery)
 	if err != nil {
@@ -282,7 +287,7 @@ func TestStorage_FilterNewsByContent(t *testing.T) {
 			initDataSqlQuery: `DELETE FROM news WHERE id IN (999999999999999999);`,
 			want: []models.NewsFullDetailed{
 				{ID: 999999999999999999, Title: "title test", Preview: "Preview test", Published: 999999999999999999,
-					Link: "https://example.com/",
+					Link: "https://example.com/", Snippet: "<mark>test</mark> <mark>filter</mark> content",
 				},
 			},
 			wantErr: false,
@@ -295,11 +300,11 @@ func TestStorage_FilterNewsByContent(t *testing.T) {
 			},
 			args: "test filter",
 			insertDataSQL: `INSERT INTO news (id,title,content,preview,published,link) VALUES 
-			(999999999999999999, 'test filter title',' content', 'Preview test', 999999999999999999, 'https://example.com/');`,
+			(999999999999999999, 'test filter title','content', 'Preview test', 999999999999999999, 'https://example.com/');`,
 			initDataSqlQuery: `DELETE FROM news WHERE id IN (999999999999999999);`,
 			want: []models.NewsFullDetailed{
 				{ID: 999999999999999999, Title: "test filter title", Preview: "Preview test", Published: 999999999999999999,
-					Link: "https://example.com/",
+					Link: "https://example.com/", Snippet: "content",
 				},
 			},
 			wantErr: false,
@@ -312,11 +317,49 @@ func TestStorage_FilterNewsByContent(t *testing.T) {
 			},
 			args: "test filter",
 			insertDataSQL: `INSERT INTO news (id,title,content,preview,published,link) VALUES 
-			(999999999999999999, 'title',' content', 'test filter Preview test', 999999999999999999, 'https://example.com/');`,
+			(999999999999999999, 'title','content', 'test filter Preview test', 999999999999999999, 'https://example.com/');`,
 			initDataSqlQuery: `DELETE FROM news WHERE id IN (999999999999999999);`,
 			want: []models.NewsFullDetailed{
 				{ID: 999999999999999999, Title: "title", Preview: "test filter Preview test", Published: 999999999999999999,
-					Link: "https://example.com/",
+					Link: "https://example.com/", Snippet: "content",
+				},
+			},
+			wantErr: false,
+		},
+
+		{
+			name: "Word forms filter test",
+			fields: fields{
+				Db: db.Db,
+			},
+			args: "новый фильтр",
+			insertDataSQL: `INSERT INTO news (id,title,content,preview,published,link) VALUES 
+			(999999999999999999, 'title','Вышли новые фильтры для поиска', 'Preview test', 999999999999999999, 'https://example.com/');`,
+			initDataSqlQuery: `DELETE FROM news WHERE id IN (999999999999999999);`,
+			want: []models.NewsFullDetailed{
+				{ID: 999999999999999999, Title: "title", Preview: "Preview test", Published: 999999999999999999,
+					Link: "https://example.com/", Snippet: "Вышли <mark>новые</mark> <mark>фильтры</mark> для поиска",
+				},
+			},
+			wantErr: false,
+		},
+
+		{
+			name: "Ranking filter test",
+			fields: fields{
+				Db: db.Db,
+			},
+			args: "test filter",
+			insertDataSQL: `INSERT INTO news (id,title,content,preview,published,link) VALUES 
+			(999999999999999999, 'title','test filter in content', 'Preview test', 999999999999999999, 'https://example.com/1'),
+			(999999999999999998, 'test filter in title','content', 'Preview test', 999999999999999998, 'https://example.com/2');`,
+			initDataSqlQuery: `DELETE FROM news WHERE id IN (999999999999999999, 999999999999999998);`,
+			want: []models.NewsFullDetailed{
+				{ID: 999999999999999998, Title: "test filter in title", Preview: "Preview test", Published: 999999999999999998,
+					Link: "https://example.com/2", Snippet: "content",
+				},
+				{ID: 999999999999999999, Title: "title", Preview: "Preview test", Published: 999999999999999999,
+					Link: "https://example.com/1", Snippet: "<mark>test</mark> <mark>filter</mark> in content",
 				},
 			},
 			wantErr: false,
@@ -353,11 +396,18 @@ func TestStorage_FilterNewsByContent(t *testing.T) {
 				_, err = db.Db.Exec(context.Background(), tt.insertDataSQL)
 				require.NoError(t, err)
 			}
-			got, err := db.FilterNewsByContent(tt.args)
+			got, err := db.FilterNewsByContent(tt.args, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Storage.FilterNewsByContent() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			//релевантность зависит от версии Postgres, проверяется только ее наличие
+			for i := range got {
+				if (tt.args != "") != (got[i].Rank > 0) {
+					t.Errorf("Storage.FilterNewsByContent() rank = %v for filter %q", got[i].Rank, tt.args)
+				}
+				got[i].Rank = 0
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Storage.FilterNewsByContent() = %v, want %v", got, tt.want)
 			}
@@ -371,6 +421,58 @@ func TestStorage_FilterNewsByContent(t *testing.T) {
 	}
 }
 
+// Тест проверяет, что поиск по непустому фильтру использует индекс news_search_idx. Последовательное
+// чтение отключается, чтобы план не зависел от числа статей в тестовой БД.
+func TestCountSearchQuery_UsesIndex(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+	defer db.Db.Close()
+
+	ctx := context.Background()
+	conn, err := db.Db.Acquire(ctx)
+	require.NoError(t, err)
+	defer conn.Release()
+	_, err = conn.Exec(ctx, `SET enable_seqscan = off;`)
+	require.NoError(t, err)
+	defer conn.Exec(ctx, `RESET enable_seqscan;`)
+
+	query, args := countSearchQuery("test filter", true)
+	rows, err := conn.Query(ctx, `EXPLAIN `+query, args...)
+	require.NoError(t, err)
+	var plan []string
+	for rows.Next() {
+		var line string
+		require.NoError(t, rows.Scan(&line))
+		plan = append(plan, line)
+	}
+	require.NoError(t, rows.Err())
+	require.Contains(t, strings.Join(plan, "\n"), "news_search_idx")
+}
+
+func TestSearchClause(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		collapse  bool
+		wantWhere string
+		wantArgs  int
+	}{
+		{name: "пустой фильтр", filter: " ", wantWhere: ""},
+		{name: "пустой фильтр без дубликатов", filter: "", collapse: true, wantWhere: " WHERE news.canonical_id IS NULL"},
+		{name: "фильтр", filter: "go", wantWhere: " WHERE news.search @@ " + searchQuery, wantArgs: 1},
+		{name: "фильтр без дубликатов", filter: "go", collapse: true,
+			wantWhere: " WHERE news.search @@ " + searchQuery + " AND news.canonical_id IS NULL", wantArgs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where, _, _, args := searchClause(tt.filter, tt.collapse)
+			if where != tt.wantWhere || len(args) != tt.wantArgs {
+				t.Errorf("searchClause() = %q, %v, want %q with %d args", where, args, tt.wantWhere, tt.wantArgs)
+			}
+		})
+	}
+}
+
 func TestStorage_FilterNewsByPublished(t *testing.T) {
 	type fields struct {
 		Db *pgxpool.Pool
@@ -442,7 +544,7 @@ func TestStorage_FilterNewsByPublished(t *testing.T) {
 				_, err = db.Db.Exec(context.Background(), tt.insertDataSQL)
 				require.NoError(t, err)
 			}
-			got, err := db.FilterNewsByPublished(tt.args)
+			got, err := db.FilterNewsByPublished(tt.args, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Storage.FilterNewsByPublished() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -459,3 +561,387 @@ func TestStorage_FilterNewsByPublished(t *testing.T) {
 		})
 	}
 }
+
+// Тест проверяет добавление, чтение, обновление и отключение источника
+func TestSourcesCRUD(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	src := models.Source{
+		URL:      "https://example.com/rss/" + strconv.Itoa(rand.Intn(999999999)),
+		Title:    "Test source",
+		Interval: 5,
+		Enabled:  true,
+		Options:  models.SourceOptions{FullText: true},
+	}
+	id, err := db.AddSource(src)
+	require.NoError(t, err)
+	defer db.Db.Exec(context.Background(), `DELETE FROM sources WHERE id = $1;`, id)
+
+	got, err := db.GetSource(id)
+	require.NoError(t, err)
+	require.Equal(t, src.URL, got.URL)
+	require.Equal(t, src.Options, got.Options)
+
+	_, err = db.AddSource(src)
+	require.ErrorIs(t, err, ErrSourceExists)
+
+	got.Title = "Updated source"
+	require.NoError(t, db.UpdateSource(got))
+	updated, err := db.GetSource(id)
+	require.NoError(t, err)
+	require.Equal(t, "Updated source", updated.Title)
+	require.Greater(t, updated.UpdatedAt, got.UpdatedAt)
+
+	require.NoError(t, db.SetSourceEnabled(id, false))
+	enabled, err := db.ListSources(true)
+	require.NoError(t, err)
+	for _, s := range enabled {
+		require.NotEqual(t, id, s.ID)
+	}
+
+	_, err = db.GetSource(-1)
+	require.ErrorIs(t, err, ErrSourceNotFound)
+}
+
+// Тест проверяет, что отклоненная хабом подписка повторяется только после отдельной задержки
+func TestSubscriptionsToRenew_Denied(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	id, err := db.AddSource(models.Source{
+		URL:     "https://example.com/rss/" + strconv.Itoa(rand.Intn(999999999)),
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	defer db.Db.Exec(context.Background(), `DELETE FROM sources WHERE id = $1;`, id)
+	require.NoError(t, db.SaveSubscription(models.Subscription{SourceID: id, Hub: "https://hub.example.com/",
+		Topic: "https://example.com/feed", Secret: "s", State: models.SubscriptionDenied}))
+
+	found := func(deniedBefore int64) bool {
+		now := time.Now().Unix()
+		subs, err := db.SubscriptionsToRenew(now+3600, now+1, deniedBefore)
+		require.NoError(t, err)
+		for _, sub := range subs {
+			if sub.SourceID == id {
+				return true
+			}
+		}
+		return false
+	}
+	require.False(t, found(time.Now().Add(-24*time.Hour).Unix()))
+	require.True(t, found(time.Now().Unix()+1))
+}
+
+func TestRecordFetch(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	id, err := db.AddSource(models.Source{
+		URL:     "https://example.com/rss/" + strconv.Itoa(rand.Intn(999999999)),
+		Enabled: true,
+	})
+	require.NoError(t, err)
+	defer db.Db.Exec(context.Background(), `DELETE FROM sources WHERE id = $1;`, id)
+
+	find := func() models.SourceHealth {
+		health, err := db.ListSourceHealth()
+		require.NoError(t, err)
+		for _, h := range health {
+			if h.SourceID == id {
+				return h
+			}
+		}
+		t.Fatalf("source %d not found in health list", id)
+		return models.SourceHealth{}
+	}
+
+	require.NoError(t, db.RecordFetch(models.FetchResult{SourceID: id, Time: 100, HTTPStatus: 200, LatencyMs: 15, Items: 7}))
+	require.NoError(t, db.RecordFetch(models.FetchResult{SourceID: id, Time: 200, Err: errors.New("timeout"), LatencyMs: 30000}))
+	require.NoError(t, db.RecordFetch(models.FetchResult{SourceID: id, Time: 300, Err: errors.New("404 Not Found"), HTTPStatus: 404}))
+
+	h := find()
+	require.Equal(t, int64(300), h.LastAttempt)
+	require.Equal(t, int64(100), h.LastSuccess)
+	require.Equal(t, 2, h.ConsecutiveFailures)
+	require.Equal(t, "404 Not Found", h.LastError)
+	require.Equal(t, 404, h.HTTPStatus)
+	require.Equal(t, 7, h.ItemsLastFetch)
+	require.Equal(t, int64(3), h.TotalFetches)
+	require.Equal(t, int64(2), h.TotalFailures)
+
+	require.NoError(t, db.RecordFetch(models.FetchResult{SourceID: id, Time: 400, HTTPStatus: 304}))
+	h = find()
+	require.Equal(t, int64(400), h.LastSuccess)
+	require.Equal(t, 0, h.ConsecutiveFailures)
+	require.Equal(t, "404 Not Found", h.LastError)
+}
+
+// Тест проверяет, что повторное добавление статьи без изменений пропускается, а измененная статья
+// обновляется с сохранением предыдущей версии
+func TestAddNews_Revisions(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	link := "https://example.com/revisions/" + strconv.Itoa(rand.Intn(999999999))
+	n := models.NewsFullDetailed{
+		Title:     "Original title",
+		Content:   "First sentence. Second sentence.",
+		Published: 1729584777,
+		Link:      link,
+	}
+	stats, err := db.AddNews([]models.NewsFullDetailed{n})
+	require.NoError(t, err)
+	require.Equal(t, models.IngestStats{Inserted: 1}, stats)
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link = $1;`, link)
+
+	var id int
+	require.NoError(t, db.Db.QueryRow(context.Background(), `SELECT id FROM news WHERE link = $1;`, link).Scan(&id))
+
+	stats, err = db.AddNews([]models.NewsFullDetailed{n})
+	require.NoError(t, err)
+	require.Equal(t, models.IngestStats{Skipped: 1}, stats)
+	revisions, err := db.ListRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+
+	n.Title = "Updated title"
+	n.Content = "First sentence. Corrected second sentence."
+	stats, err = db.AddNews([]models.NewsFullDetailed{n})
+	require.NoError(t, err)
+	require.Equal(t, models.IngestStats{Updated: 1}, stats)
+
+	revisions, err = db.ListRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "Original title", revisions[0].Title)
+	require.False(t, revisions[0].Current)
+	require.Equal(t, 2, revisions[1].Revision)
+	require.Equal(t, "Updated title", revisions[1].Title)
+	require.True(t, revisions[1].Current)
+
+	detailed, err := db.GetDetailedNews(id)
+	require.NoError(t, err)
+	require.Equal(t, "Updated title", detailed.Title)
+}
+
+// Тест проверяет, что уже сохраненная ссылка и повтор внутри пакета не прерывают сохранение остальных
+// статей пакета, а почти дубликат внутри пакета связывается с исходной статьей того же пакета
+func TestAddNews_Batch(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	prefix := "https://example.com/batch/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+
+	seen := models.NewsFullDetailed{Title: "Seen", Content: "Already stored article.", Published: 1729584700, Link: prefix + "seen"}
+	_, err = db.AddNews([]models.NewsFullDetailed{seen})
+	require.NoError(t, err)
+
+	original := models.NewsFullDetailed{
+		Title:     "Центробанк сохранил ключевую ставку на прежнем уровне",
+		Content:   "Совет директоров Банка России принял решение сохранить ключевую ставку, сообщила пресс-служба регулятора.",
+		Published: 1729584701,
+		Link:      prefix + "original",
+	}
+	copied := original
+	copied.Link = prefix + "copy"
+	fresh := models.NewsFullDetailed{Title: "Fresh", Content: "Brand new article.", Published: 1729584702, Link: prefix + "fresh"}
+
+	stats, err := db.AddNews([]models.NewsFullDetailed{seen, original, fresh, copied, fresh})
+	require.NoError(t, err)
+	require.Equal(t, models.IngestStats{Inserted: 3, Skipped: 2}, stats)
+
+	var canonical, originalID int
+	require.NoError(t, db.Db.QueryRow(context.Background(), `SELECT id FROM news WHERE link = $1;`, original.Link).Scan(&originalID))
+	require.NoError(t, db.Db.QueryRow(context.Background(), `SELECT COALESCE(canonical_id,0) FROM news WHERE link = $1;`,
+		copied.Link).Scan(&canonical))
+	require.Equal(t, originalID, canonical)
+}
+
+// Тест проверяет, что не датированная статья не расширяет поиск почти дубликатов за пределы окна от текущего времени
+func TestAddNews_DuplicateWindow(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	prefix := "https://example.com/window/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+
+	original := models.NewsFullDetailed{
+		Title:     "Минфин разместил облигации федерального займа на крупную сумму",
+		Content:   "Министерство финансов провело аукционы по размещению ОФЗ, сообщило ведомство.",
+		Published: 1729584900,
+		Link:      prefix + "original",
+	}
+	_, err = db.AddNews([]models.NewsFullDetailed{original})
+	require.NoError(t, err)
+
+	copied := original
+	copied.Published = 0
+	copied.Link = prefix + "copy"
+	_, err = db.AddNews([]models.NewsFullDetailed{copied})
+	require.NoError(t, err)
+
+	var canonical int
+	require.NoError(t, db.Db.QueryRow(context.Background(), `SELECT COALESCE(canonical_id,0) FROM news WHERE link = $1;`,
+		copied.Link).Scan(&canonical))
+	require.Zero(t, canonical)
+}
+
+func TestKeysetPage(t *testing.T) {
+	columns := []string{"published", "id"}
+	tests := []struct {
+		name     string
+		cursor   *models.Cursor
+		wantPage string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "первая страница",
+			wantPage: "ORDER BY published DESC, id DESC LIMIT $2",
+			wantArgs: []interface{}{11},
+		},
+		{
+			name:     "после курсора",
+			cursor:   &models.Cursor{Published: 100, ID: 5},
+			wantPage: "WHERE (published,id) < ($2::BIGINT,$3::BIGINT) ORDER BY published DESC, id DESC LIMIT $4",
+			wantArgs: []interface{}{int64(100), 5, 11},
+		},
+		{
+			name:     "перед курсором",
+			cursor:   &models.Cursor{Published: 100, ID: 5, Before: true},
+			wantPage: "WHERE (published,id) > ($2::BIGINT,$3::BIGINT) ORDER BY published ASC, id ASC LIMIT $4",
+			wantArgs: []interface{}{int64(100), 5, 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key []interface{}
+			if tt.cursor != nil {
+				key = []interface{}{tt.cursor.Published, tt.cursor.ID}
+			}
+			page, args := keysetPage(columns, key, tt.cursor, 10, 2)
+			require.Equal(t, tt.wantPage, page)
+			require.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+// Тест проверяет проход списка новостей по курсору вперед и назад: страницы не пересекаются и не зависят
+// от новостей, добавленных после получения первой страницы
+func TestGetNewsListByCursor(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	const base = 999999999999999900
+	prefix := "https://example.com/cursor/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+	for i := 0; i < 5; i++ {
+		_, err = db.Db.Exec(context.Background(), `INSERT INTO news (title,content,published,link) VALUES ($1,'',$2,$3);`,
+			"Cursor "+strconv.Itoa(i), base, prefix+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+
+	first, more, err := db.GetNewsListByCursor(nil, 2, false)
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Len(t, first, 2)
+	require.True(t, first[0].ID > first[1].ID)
+
+	_, err = db.Db.Exec(context.Background(), `INSERT INTO news (title,content,published,link) VALUES ('Newer','',$1,$2);`,
+		base+1, prefix+"newer")
+	require.NoError(t, err)
+
+	last := first[1]
+	second, _, err := db.GetNewsListByCursor(&models.Cursor{Published: last.Published, ID: last.ID}, 2, false)
+	require.NoError(t, err)
+	require.Len(t, second, 2)
+	require.True(t, second[0].ID < last.ID)
+
+	back, more, err := db.GetNewsListByCursor(&models.Cursor{Published: second[0].Published, ID: second[0].ID, Before: true}, 2, false)
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Equal(t, first, back)
+}
+
+// Тест проверяет проход по курсору новостей с заданной датой публикации: новости с другой датой не попадают
+// на страницы
+func TestFilterNewsByPublishedByCursor(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	const date = 999999999999999800
+	prefix := "https://example.com/date-cursor/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+	for i := 0; i < 3; i++ {
+		_, err = db.Db.Exec(context.Background(), `INSERT INTO news (title,content,published,link) VALUES ($1,'',$2,$3);`,
+			"Date "+strconv.Itoa(i), date, prefix+strconv.Itoa(i))
+		require.NoError(t, err)
+	}
+	_, err = db.Db.Exec(context.Background(), `INSERT INTO news (title,content,published,link) VALUES ('Other','',$1,$2);`,
+		date-1, prefix+"other")
+	require.NoError(t, err)
+
+	first, more, err := db.FilterNewsByPublishedByCursor(date, nil, 2, false)
+	require.NoError(t, err)
+	require.True(t, more)
+	require.Len(t, first, 2)
+
+	last := first[1]
+	second, more, err := db.FilterNewsByPublishedByCursor(date, &models.Cursor{Published: last.Published, ID: last.ID}, 2, false)
+	require.NoError(t, err)
+	require.False(t, more)
+	require.Len(t, second, 1)
+	require.Equal(t, int64(date), second[0].Published)
+}
+
+// Тест проверяет, что статья другого источника с тем же GUID сохраняется отдельно и не обновляет чужую статью
+func TestAddNews_GUIDPerSource(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	prefix := "https://example.com/guid/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	var sources []int
+	for _, name := range []string{"a", "b"} {
+		id, err := db.AddSource(models.Source{URL: prefix + name + "/feed", Enabled: true})
+		require.NoError(t, err)
+		sources = append(sources, id)
+	}
+	defer db.Db.Exec(context.Background(), `DELETE FROM sources WHERE url LIKE $1;`, prefix+"%")
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+
+	a := models.NewsFullDetailed{Title: "Article A", Content: "Content A", Published: 1729584800, Link: prefix + "a/1",
+		GUID: "1", SourceID: sources[0]}
+	_, err = db.AddNews([]models.NewsFullDetailed{a})
+	require.NoError(t, err)
+
+	b := models.NewsFullDetailed{Title: "Article B", Content: "Content B", Published: 1729584801, Link: prefix + "b/1",
+		GUID: "1", SourceID: sources[1]}
+	stats, err := db.AddNews([]models.NewsFullDetailed{b})
+	require.NoError(t, err)
+	require.Equal(t, models.IngestStats{Inserted: 1}, stats)
+
+	var id int
+	require.NoError(t, db.Db.QueryRow(context.Background(), `SELECT id FROM news WHERE link = $1;`, a.Link).Scan(&id))
+	revisions, err := db.ListRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, "Article A", revisions[0].Title)
+}
+
+// Тест проверяет, что статья находится и по ссылке, и по каноническому ключу, если ключ взят
+// из канонической ссылки со страницы
+func TestExistingLinks(t *testing.T) {
+	db, err := NewMock()
+	require.NoError(t, err)
+
+	prefix := "https://example.com/existing/" + strconv.Itoa(rand.Intn(999999999)) + "/"
+	defer db.Db.Exec(context.Background(), `DELETE FROM news WHERE link LIKE $1;`, prefix+"%")
+	_, err = db.AddNews([]models.NewsFullDetailed{{Title: "Canonical", Content: "Text", Published: 1729584900,
+		Link: prefix + "feed?utm_source=rss", LinkKey: prefix + "canonical"}})
+	require.NoError(t, err)
+
+	existing, err := db.ExistingLinks([]string{prefix + "canonical", prefix + "feed?utm_source=rss", prefix + "other"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{prefix + "canonical": true, prefix + "feed?utm_source=rss": true}, existing)
+}
@@ -0,0 +1,122 @@
+package postgress
+
+import (
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Сохраненная версия статьи, с которой сравнивается полученная из источника
+type storedNews struct {
+	id          int
+	hash        string
+	title       string
+	content     string
+	contentHTML string
+	updated     int64
+}
+
+// Функция расчета хэша содержимого статьи. Полный текст со страницы не учитывается: он загружается
+// только для новых статей.
+func contentHash(title, content, contentHTML string) string {
+	h := sha256.New()
+	for _, s := range []string{title, content, contentHTML} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GUID статьи в источнике. GUID уникален только внутри ленты: разные источники могут использовать
+// одинаковые короткие или числовые GUID.
+type guidKey struct {
+	sourceID int
+	guid     string
+}
+
+// Поиск сохраненных статей по ключам ссылок и GUID источников. Возвращает статьи, сохраненные с указанными ключами
+// и с указанными GUID. Найденные статьи блокируются до конца транзакции, чтобы параллельное обновление той же
+// статьи дождалось текущего и сравнивалось уже с новой версией.
+func findStoredNews(ctx context.Context, tx pgx.Tx, keys []string, guids []guidKey) (byKey map[string]storedNews, byGUID map[guidKey]storedNews, err error) {
+	sourceIDs := make([]int, len(guids))
+	guidValues := make([]string, len(guids))
+	for i, g := range guids {
+		sourceIDs[i], guidValues[i] = g.sourceID, g.guid
+	}
+	rows, err := tx.Query(ctx, `SELECT id,link_key,guid,COALESCE(source_id,0),content_hash,title,COALESCE(content,''),content_html,
+	COALESCE(NULLIF(updated_at,0),published,0)
+	FROM news WHERE link_key = ANY($1) OR (guid <> '' AND (COALESCE(source_id,0),guid) IN
+	(SELECT * FROM unnest($2::BIGINT[],$3::TEXT[])))
+	ORDER BY id FOR UPDATE;`, keys, sourceIDs, guidValues)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	byKey = make(map[string]storedNews)
+	byGUID = make(map[guidKey]storedNews)
+	for rows.Next() {
+		var n storedNews
+		var key, guid string
+		var sourceID int
+		err = rows.Scan(&n.id, &key, &guid, &sourceID, &n.hash, &n.title, &n.content, &n.contentHTML, &n.updated)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		//статьи, сохраненные до появления content_hash
+		if n.hash == "" {
+			n.hash = contentHash(n.title, n.content, n.contentHTML)
+		}
+		byKey[key] = n
+		if guid != "" {
+			byGUID[guidKey{sourceID, guid}] = n
+		}
+	}
+	return byKey, byGUID, rows.Err()
+}
+
+// Постановка в пакет запросов обновления статьи с сохранением предыдущей версии. Полный текст заменяется,
+// только если он был загружен заново. Номер версии берется после последней сохраненной; строка статьи
+// заблокирована в findStoredNews, а уникальный индекс (news_id, revision) не дает записать один номер дважды.
+func queueUpdate(batch *pgx.Batch, stored storedNews, n models.NewsFullDetailed, hash string, now int64) {
+	batch.Queue(`INSERT INTO news_revisions (news_id,revision,title,content,content_html,created_at)
+	VALUES ($1,(SELECT COALESCE(MAX(revision),0)+1 FROM news_revisions WHERE news_id = $1),$2,$3,$4,$5);`,
+		stored.id, stored.title, stored.content, stored.contentHTML, stored.updated)
+	batch.Queue(`UPDATE news SET title = $2, content = $3, content_html = $4, preview = $5,
+	full_content = CASE WHEN $6 <> '' THEN $6 ELSE full_content END, author = $7, categories = $8, enclosures = $9,
+	image_url = $10, fingerprint = $11, content_hash = $12, updated_at = $13 WHERE id = $1;`,
+		stored.id, n.Title, n.Content, n.ContentHTML, n.Preview, n.FullContent, n.Author, n.Categories, n.Enclosures,
+		n.ImageURL, n.Fingerprint, hash, now)
+}
+
+// Метод получения всех версий статьи по порядку. Последней возвращается текущая версия статьи.
+func (s *Storage) ListRevisions(newsID int) ([]models.Revision, error) {
+	rows, err := s.Db.Query(context.Background(), `SELECT revision,title,content,content_html,created_at,false
+	FROM news_revisions WHERE news_id = $1
+	UNION ALL
+	SELECT (SELECT COUNT(*)+1 FROM news_revisions WHERE news_id = $1),title,COALESCE(content,''),content_html,
+	COALESCE(NULLIF(updated_at,0),published,0),true
+	FROM news WHERE id = $1
+	ORDER BY 1;`, newsID)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.Revision{}
+	for rows.Next() {
+		var r models.Revision
+		err = rows.Scan(&r.Revision, &r.Title, &r.Content, &r.ContentHTML, &r.CreatedAt, &r.Current)
+		if err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
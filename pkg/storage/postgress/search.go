@@ -0,0 +1,161 @@
+package postgress
+
+import (
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Поисковый запрос по параметру $1: строка в синтаксисе websearch_to_tsquery (слова, "фраза", or, -слово)
+// с русской и английской морфологией
+const searchQuery = `(websearch_to_tsquery('russian', $1) || websearch_to_tsquery('english', $1))`
+
+// Параметры фрагментов с найденными словами. Текст экранируется до выделения, поэтому фрагмент
+// можно выводить как HTML.
+const headlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxWords=35, MinWords=15, MaxFragments=2`
+
+// Функция построения частей запроса поиска. Возвращает условие отбора статей (пустое, если отбирать
+// нечего), выражения релевантности статьи news и фрагмента текста статьи found и параметры запроса.
+// Условие по поисковому запросу подставляется в SQL только для непустого фильтра, чтобы планировщик
+// использовал индекс news_search_idx. collapse - не возвращать почти дубликаты.
+func searchClause(filter string, collapse bool) (where, rank, snippet string, args []interface{}) {
+	var conds []string
+	rank, snippet = `0::REAL`, `''`
+	if filter = strings.TrimSpace(filter); filter != "" {
+		conds = append(conds, `news.search @@ `+searchQuery)
+		rank = `ts_rank(news.search, ` + searchQuery + `)`
+		snippet = `ts_headline('russian',
+		replace(replace(replace(COALESCE(found.content,''),'&','&amp;'),'<','&lt;'),'>','&gt;'),
+		` + searchQuery + `, '` + headlineOptions + `')`
+		args = append(args, filter)
+	}
+	if collapse {
+		conds = append(conds, `news.canonical_id IS NULL`)
+	}
+	if len(conds) > 0 {
+		where = ` WHERE ` + strings.Join(conds, ` AND `)
+	}
+	return where, rank, snippet, args
+}
+
+// Функция построения запроса подсчета статей, найденных по поисковому запросу
+func countSearchQuery(filter string, collapse bool) (string, []interface{}) {
+	where, _, _, args := searchClause(filter, collapse)
+	return `SELECT COUNT(*) FROM news` + where + `;`, args
+}
+
+// Метод подсчета количества статей, найденных по поисковому запросу
+func (s *Storage) CountNewsByContent(filter string, collapse bool) (int, error) {
+	var count int
+	query, args := countSearchQuery(filter, collapse)
+	err := s.Db.QueryRow(context.Background(), query, args...).Scan(&count)
+	if err != nil {
+		log.Printf("cant read filtered data from database: %v\n", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// Метод полнотекстового поиска новостей с выводом по курсору. cursor - позиция, от которой выбирается страница
+// (nil - первая страница). Возвращает не больше limit статей и признак наличия статей за пределами страницы
+// в направлении курсора.
+func (s *Storage) SearchNewsByCursor(filter string, cursor *models.Cursor, limit int, collapse bool) ([]models.NewsFullDetailed, bool, error) {
+	var key []interface{}
+	if cursor != nil {
+		key = []interface{}{cursor.Rank, cursor.Published, cursor.ID}
+	}
+	news, err := s.searchNews(filter, collapse, func(n int) (string, []interface{}) {
+		return keysetPage([]string{"rank", "published", "id"}, key, cursor, limit, n)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	news, more := trimPage(news, cursor, limit)
+	return news, more, nil
+}
+
+// Поиск статей по убыванию релевантности (ts_rank), при равной релевантности - по дате публикации.
+// page возвращает условие, порядок и ограничение выборки страницы с параметрами, нумерация которых
+// начинается с $n. Фрагменты ts_headline строятся только для статей страницы.
+func (s *Storage) searchNews(filter string, collapse bool, page func(n int) (string, []interface{})) ([]models.NewsFullDetailed, error) {
+	where, rank, snippet, args := searchClause(filter, collapse)
+	pageSQL, pageArgs := page(len(args) + 1)
+	query := `WITH ranked AS (SELECT news.id,news.title,news.preview,news.published,news.link,news.author,
+	news.image_url,news.content,` + rank + ` AS rank
+	FROM news` + where + `),
+	found AS (SELECT * FROM ranked ` + pageSQL + `)
+	SELECT found.id,found.title,found.preview,found.published,found.link,found.author,found.image_url,found.rank,
+	` + snippet + `
+	FROM found ORDER BY found.rank DESC, found.published DESC, found.id DESC;`
+	rows, err := s.Db.Query(context.Background(), query, append(args, pageArgs...)...)
+	if err != nil {
+		log.Printf("cant read filtered data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	news := []models.NewsFullDetailed{}
+	for rows.Next() {
+		new := models.NewsFullDetailed{}
+		err = rows.Scan(
+			&new.ID,
+			&new.Title,
+			&new.Preview,
+			&new.Published,
+			&new.Link,
+			&new.Author,
+			&new.ImageURL,
+			&new.Rank,
+			&new.Snippet,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		news = append(news, new)
+	}
+	return news, rows.Err()
+}
+
+// Типы столбцов ключа сортировки
+var keyTypes = map[string]string{"rank": "REAL", "published": "BIGINT", "id": "BIGINT"}
+
+// Функция построения условия, порядка и ограничения выборки страницы по курсору для списка, упорядоченного
+// по убыванию столбцов columns. key - значения столбцов в позиции курсора, параметры нумеруются с $n.
+// Выбирается на одну статью больше limit, чтобы узнать, есть ли статьи за пределами страницы.
+func keysetPage(columns []string, key []interface{}, cursor *models.Cursor, limit, n int) (string, []interface{}) {
+	order := " DESC"
+	var where string
+	var args []interface{}
+	if cursor != nil {
+		op := "<"
+		if cursor.Before {
+			op, order = ">", " ASC"
+		}
+		params := make([]string, len(columns))
+		for i, c := range columns {
+			params[i] = fmt.Sprintf("$%d::%s", n+i, keyTypes[c])
+		}
+		where = "WHERE (" + strings.Join(columns, ",") + ") " + op + " (" + strings.Join(params, ",") + ") "
+		args = append(args, key...)
+	}
+	sort := make([]string, len(columns))
+	for i, c := range columns {
+		sort[i] = c + order
+	}
+	args = append(args, limit+1)
+	return where + "ORDER BY " + strings.Join(sort, ", ") + fmt.Sprintf(" LIMIT $%d", n+len(args)-1), args
+}
+
+// Функция отбрасывания лишней статьи, выбранной для проверки наличия следующей страницы. Статьи упорядочены
+// по убыванию, поэтому при выборке перед курсором лишняя статья - первая.
+func trimPage(news []models.NewsFullDetailed, cursor *models.Cursor, limit int) ([]models.NewsFullDetailed, bool) {
+	if len(news) <= limit {
+		return news, false
+	}
+	if cursor != nil && cursor.Before {
+		return news[1:], true
+	}
+	return news[:limit], true
+}
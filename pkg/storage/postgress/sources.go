@@ -0,0 +1,153 @@
+package postgress
+
+import (
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// Ошибка - источник с заданным ID не найден
+var ErrSourceNotFound = errors.New("source not found")
+
+// Ошибка - источник с таким URL уже сохранен
+var ErrSourceExists = errors.New("source with this url already exists")
+
+// Поля источника в порядке сканирования функцией scanSource
+const sourceColumns = `id,url,title,group_name,interval,enabled,options,updated_at`
+
+// Метод добавления источника в БД. Возвращает ID созданного источника.
+func (s *Storage) AddSource(src models.Source) (int, error) {
+	var id int
+	err := s.Db.QueryRow(context.Background(), `INSERT INTO sources (url,title,group_name,interval,enabled,options,updated_at)
+	VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id;`,
+		src.URL, src.Title, src.Group, src.Interval, src.Enabled, src.Options, time.Now().Unix()).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrSourceExists
+	}
+	if err != nil {
+		log.Printf("Cant add source in database! %v\n", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// Метод добавления списка источников в одной транзакции. Источники с уже сохраненным URL пропускаются.
+// Возвращает число добавленных источников.
+func (s *Storage) AddSources(sources []models.Source) (int, error) {
+	ctx := context.Background()
+	tx, err := s.Db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	added := 0
+	now := time.Now().Unix()
+	for _, src := range sources {
+		tag, err := tx.Exec(ctx, `INSERT INTO sources (url,title,group_name,interval,enabled,options,updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT (url) DO NOTHING;`,
+			src.URL, src.Title, src.Group, src.Interval, src.Enabled, src.Options, now)
+		if err != nil {
+			log.Printf("Cant add source in database! %v\n", err)
+			return 0, err
+		}
+		added += int(tag.RowsAffected())
+	}
+	return added, tx.Commit(ctx)
+}
+
+// Метод получения источника по ID
+func (s *Storage) GetSource(id int) (models.Source, error) {
+	row := s.Db.QueryRow(context.Background(), `SELECT `+sourceColumns+` FROM sources WHERE id = $1;`, id)
+	src, err := scanSource(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Source{}, ErrSourceNotFound
+	}
+	if err != nil {
+		return models.Source{}, fmt.Errorf("unable scan row: %w", err)
+	}
+	return src, nil
+}
+
+// Метод получения списка источников. enabledOnly - вернуть только включенные источники.
+func (s *Storage) ListSources(enabledOnly bool) ([]models.Source, error) {
+	rows, err := s.Db.Query(context.Background(), `SELECT `+sourceColumns+` FROM sources
+	WHERE enabled OR NOT $1 ORDER BY id;`, enabledOnly)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []models.Source{}
+	for rows.Next() {
+		src, err := scanSource(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		sources = append(sources, src)
+	}
+	return sources, rows.Err()
+}
+
+// Метод обновления настроек источника. updated_at строго возрастает, по нему планировщик определяет,
+// что настройки источника изменились.
+func (s *Storage) UpdateSource(src models.Source) error {
+	tag, err := s.Db.Exec(context.Background(), `UPDATE sources SET url=$2,title=$3,group_name=$4,interval=$5,enabled=$6,
+	options=$7,updated_at=GREATEST(updated_at+1,$8) WHERE id = $1;`,
+		src.ID, src.URL, src.Title, src.Group, src.Interval, src.Enabled, src.Options, time.Now().Unix())
+	if isUniqueViolation(err) {
+		return ErrSourceExists
+	}
+	if err != nil {
+		log.Printf("Cant update source in database! %v\n", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSourceNotFound
+	}
+	return nil
+}
+
+// Метод включения/отключения опроса источника
+func (s *Storage) SetSourceEnabled(id int, enabled bool) error {
+	tag, err := s.Db.Exec(context.Background(), `UPDATE sources SET enabled=$2,updated_at=GREATEST(updated_at+1,$3)
+	WHERE id = $1;`,
+		id, enabled, time.Now().Unix())
+	if err != nil {
+		log.Printf("Cant update source in database! %v\n", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSourceNotFound
+	}
+	return nil
+}
+
+// Функция сканирования строки таблицы sources
+func scanSource(row pgx.Row) (models.Source, error) {
+	var src models.Source
+	err := row.Scan(
+		&src.ID,
+		&src.URL,
+		&src.Title,
+		&src.Group,
+		&src.Interval,
+		&src.Enabled,
+		&src.Options,
+		&src.UpdatedAt,
+	)
+	return src, err
+}
+
+// Функция проверяет, что ошибка - нарушение ограничения уникальности (код 23505)
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
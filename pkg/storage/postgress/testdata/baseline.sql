@@ -1,4 +1,3 @@
-DROP TABLE IF EXISTS news,shortnews;
 CREATE TABLE news (
   id BIGSERIAL PRIMARY KEY,
   title TEXT NOT NULL,
@@ -0,0 +1,103 @@
+package postgress
+
+import (
+	models "Skillfactory/36-GoNews/pkg/storage/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Ошибка - подписка WebSub для источника не найдена
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// Поля подписки в порядке сканирования функцией scanSubscription
+const subscriptionColumns = `source_id,hub,topic,secret,state,lease_expires,updated_at`
+
+// Метод сохранения подписки источника. Существующая подписка заменяется.
+func (s *Storage) SaveSubscription(sub models.Subscription) error {
+	_, err := s.Db.Exec(context.Background(), `INSERT INTO websub_subscriptions (`+subscriptionColumns+`)
+	VALUES ($1,$2,$3,$4,$5,$6,$7)
+	ON CONFLICT (source_id) DO UPDATE SET hub = EXCLUDED.hub, topic = EXCLUDED.topic, secret = EXCLUDED.secret,
+	state = EXCLUDED.state, lease_expires = EXCLUDED.lease_expires, updated_at = EXCLUDED.updated_at;`,
+		sub.SourceID, sub.Hub, sub.Topic, sub.Secret, sub.State, sub.LeaseExpires, time.Now().Unix())
+	if err != nil {
+		log.Printf("Cant save subscription in database! %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// Метод получения подписки по ID источника
+func (s *Storage) GetSubscription(sourceID int) (models.Subscription, error) {
+	row := s.Db.QueryRow(context.Background(), `SELECT `+subscriptionColumns+` FROM websub_subscriptions
+	WHERE source_id = $1;`, sourceID)
+	sub, err := scanSubscription(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Subscription{}, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return models.Subscription{}, fmt.Errorf("unable scan row: %w", err)
+	}
+	return sub, nil
+}
+
+// Метод получения подписок включенных источников, которые нужно продлить или повторить: активные подписки,
+// срок которых истекает раньше expiresBefore, и неподтвержденные подписки. Подписки, запрос по которым
+// отправлялся позже retryBefore, пропускаются до ответа хаба. Отклоненные хабом подписки повторяются,
+// только если отказ получен раньше deniedBefore.
+func (s *Storage) SubscriptionsToRenew(expiresBefore, retryBefore, deniedBefore int64) ([]models.Subscription, error) {
+	rows, err := s.Db.Query(context.Background(), `SELECT `+prefixed("w.", subscriptionColumns)+`
+	FROM websub_subscriptions w JOIN sources s ON s.id = w.source_id
+	WHERE s.enabled AND CASE w.state
+		WHEN 'active' THEN w.lease_expires < $1 AND w.updated_at < $2
+		WHEN 'denied' THEN w.updated_at < $3
+		ELSE w.updated_at < $2
+	END
+	ORDER BY w.source_id;`, expiresBefore, retryBefore, deniedBefore)
+	if err != nil {
+		log.Printf("Cant read data from database: %v\n", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.Subscription{}
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("unable scan row: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Метод подтверждения подписки хабом со сроком lease
+func (s *Storage) ActivateSubscription(sourceID int, lease time.Duration) error {
+	now := time.Now()
+	_, err := s.Db.Exec(context.Background(), `UPDATE websub_subscriptions SET state = 'active', lease_expires = $2,
+	updated_at = $3 WHERE source_id = $1;`, sourceID, now.Add(lease).Unix(), now.Unix())
+	return err
+}
+
+// Метод изменения состояния подписки
+func (s *Storage) SetSubscriptionState(sourceID int, state string) error {
+	_, err := s.Db.Exec(context.Background(), `UPDATE websub_subscriptions SET state = $2, updated_at = $3
+	WHERE source_id = $1;`, sourceID, state, time.Now().Unix())
+	return err
+}
+
+func scanSubscription(row pgx.Row) (models.Subscription, error) {
+	var sub models.Subscription
+	err := row.Scan(&sub.SourceID, &sub.Hub, &sub.Topic, &sub.Secret, &sub.State, &sub.LeaseExpires, &sub.UpdatedAt)
+	return sub, err
+}
+
+// Функция добавления префикса таблицы к списку полей
+func prefixed(prefix, columns string) string {
+	return prefix + strings.ReplaceAll(columns, ",", ","+prefix)
+}
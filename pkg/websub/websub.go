@@ -0,0 +1,168 @@
+package websub
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Режимы запросов к хабу и запросов подтверждения от хаба
+const (
+	ModeSubscribe = "subscribe"
+	ModeDenied    = "denied"
+)
+
+// Минимальный срок подписки, от которого планируется продление. Меньший или не указанный хабом срок
+// увеличивается до него, чтобы подписка не продлевалась при каждой проверке.
+const MinLease = 2 * time.Hour
+
+// Запрос подписки
+type Request struct {
+	Hub      string
+	Topic    string        //URL ленты (rel="self")
+	Callback string        //URL, на который хаб отправляет подтверждение и новые записи
+	Secret   string        //ключ подписи X-Hub-Signature
+	Lease    time.Duration //желаемый срок подписки, итоговый срок сообщает хаб при подтверждении
+}
+
+// Клиент хаба WebSub
+type Client struct {
+	HTTP *http.Client
+}
+
+// Метод отправки запроса подписки. Хаб подтверждает подписку асинхронно GET-запросом на Callback,
+// повторный запрос с тем же Callback продлевает подписку.
+func (c *Client) Subscribe(ctx context.Context, r Request) error {
+	form := url.Values{
+		"hub.mode":     {ModeSubscribe},
+		"hub.topic":    {r.Topic},
+		"hub.callback": {r.Callback},
+	}
+	if r.Secret != "" {
+		form.Set("hub.secret", r.Secret)
+	}
+	if r.Lease > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(int(r.Lease.Seconds())))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.Hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	//хаб отвечает 202 Accepted, некоторые хабы - 204 No Content
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub %s rejected subscription: %s %s", r.Hub, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Функция разбора срока подписки из параметра hub.lease_seconds подтверждения хаба. Срок меньше MinLease,
+// нулевой или некорректный возвращается как MinLease.
+func ParseLease(seconds string) time.Duration {
+	n, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil || n < int64(MinLease/time.Second) {
+		return MinLease
+	}
+	return time.Duration(n) * time.Second
+}
+
+// Функция поиска в ленте ссылок на хаб (rel="hub") и на саму ленту (rel="self"). Поддерживаются
+// <link> Atom и <atom:link> в RSS. Поиск прекращается на первой записи ленты.
+func FindHub(body []byte) (hub, self string) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = false
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return hub, self
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch el.Name.Local {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, a := range el.Attr {
+				switch a.Name.Local {
+				case "rel":
+					rel = a.Value
+				case "href":
+					href = strings.TrimSpace(a.Value)
+				}
+			}
+			for _, r := range strings.Fields(rel) {
+				if r == "hub" && hub == "" {
+					hub = href
+				}
+				if r == "self" && self == "" {
+					self = href
+				}
+			}
+		}
+	}
+}
+
+// Функция проверки подписи тела уведомления из заголовка X-Hub-Signature вида "sha256=<hex>".
+func CheckSignature(header string, body []byte, secret string) bool {
+	method, sig, ok := strings.Cut(header, "=")
+	if !ok || secret == "" {
+		return false
+	}
+	var h func() hash.Hash
+	switch method {
+	case "sha1":
+		h = sha1.New
+	case "sha256":
+		h = sha256.New
+	case "sha384":
+		h = sha512.New384
+	case "sha512":
+		h = sha512.New
+	default:
+		return false
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(h, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// Функция генерации случайного ключа подписи
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,149 @@
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFindHub(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantHub  string
+		wantSelf string
+	}{
+		{
+			name: "Atom",
+			body: `<?xml version="1.0" encoding="utf-8"?><feed xmlns="http://www.w3.org/2005/Atom">
+			<link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+			<link rel="self" href="https://example.com/feed.atom"/>
+			<entry><link rel="alternate" href="https://example.com/1"/></entry></feed>`,
+			wantHub:  "https://pubsubhubbub.appspot.com/",
+			wantSelf: "https://example.com/feed.atom",
+		},
+		{
+			name: "RSS with atom:link",
+			body: `<?xml version="1.0" encoding="windows-1251"?><rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<atom:link href="https://example.com/rss" rel="self" type="application/rss+xml"/>
+			<atom:link href="https://websub.example.com/" rel="hub"/>
+			<link>https://example.com/</link></channel></rss>`,
+			wantHub:  "https://websub.example.com/",
+			wantSelf: "https://example.com/rss",
+		},
+		{
+			name: "Hub inside item is ignored",
+			body: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+			<item><atom:link href="https://websub.example.com/" rel="hub"/></item></channel></rss>`,
+		},
+		{
+			name: "Not XML",
+			body: `<html><body>not a feed`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, self := FindHub([]byte(tt.body))
+			if hub != tt.wantHub || self != tt.wantSelf {
+				t.Errorf("FindHub() = %q, %q, want %q, %q", hub, self, tt.wantHub, tt.wantSelf)
+			}
+		})
+	}
+}
+
+func TestClient_Subscribe(t *testing.T) {
+	status := http.StatusAccepted
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]string{
+			"hub.mode":          "subscribe",
+			"hub.topic":         "https://example.com/feed.atom",
+			"hub.callback":      "https://news.example.com/websub/1",
+			"hub.secret":        "secret",
+			"hub.lease_seconds": "86400",
+		}
+		for k, v := range want {
+			if got := r.PostForm.Get(k); got != v {
+				t.Errorf("%s = %q, want %q", k, got, v)
+			}
+		}
+		w.WriteHeader(status)
+	}))
+	defer hub.Close()
+
+	c := Client{HTTP: hub.Client()}
+	req := Request{
+		Hub:      hub.URL,
+		Topic:    "https://example.com/feed.atom",
+		Callback: "https://news.example.com/websub/1",
+		Secret:   "secret",
+		Lease:    24 * time.Hour,
+	}
+	if err := c.Subscribe(context.Background(), req); err != nil {
+		t.Errorf("Subscribe() error = %v", err)
+	}
+	status = http.StatusBadRequest
+	if err := c.Subscribe(context.Background(), req); err == nil {
+		t.Error("Subscribe() returned no error for rejected request")
+	}
+}
+
+func TestParseLease(t *testing.T) {
+	tests := []struct {
+		seconds string
+		want    time.Duration
+	}{
+		{seconds: "604800", want: 7 * 24 * time.Hour},
+		{seconds: "0", want: MinLease},
+		{seconds: "", want: MinLease},
+		{seconds: "60", want: MinLease},
+		{seconds: "-1", want: MinLease},
+		{seconds: "week", want: MinLease},
+	}
+	for _, tt := range tests {
+		if got := ParseLease(tt.seconds); got != tt.want {
+			t.Errorf("ParseLease(%q) = %v, want %v", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestCheckSignature(t *testing.T) {
+	body := []byte(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`)
+	sign := func(method string, secret string) string {
+		h := hmac.New(sha256.New, []byte(secret))
+		if method == "sha1" {
+			h = hmac.New(sha1.New, []byte(secret))
+		}
+		h.Write(body)
+		return method + "=" + hex.EncodeToString(h.Sum(nil))
+	}
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		want   bool
+	}{
+		{name: "sha256", header: sign("sha256", "secret"), secret: "secret", want: true},
+		{name: "sha1", header: sign("sha1", "secret"), secret: "secret", want: true},
+		{name: "Wrong secret", header: sign("sha256", "other"), secret: "secret"},
+		{name: "Unknown method", header: "md5=00", secret: "secret"},
+		{name: "Missing header", header: "", secret: "secret"},
+		{name: "Not hex", header: "sha256=zz", secret: "secret"},
+		{name: "Empty secret", header: sign("sha256", ""), secret: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckSignature(tt.header, body, tt.secret); got != tt.want {
+				t.Errorf("CheckSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}